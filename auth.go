@@ -0,0 +1,262 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/MicahParks/keyfunc/v3"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Claims описывает то, что мы знаем о вызывающей стороне после проверки токена.
+type Claims struct {
+	User    string   `json:"user"`
+	Actions []string `json:"actions"`
+	Tunnels []string `json:"tunnels"` // разрешённые шаблоны tunnel/path, например "cam-*"
+}
+
+type contextKey string
+
+const claimsContextKey contextKey = "claims"
+
+var (
+	authMethod    string
+	jwtJWKSURL    string
+	jwtIssuer     string
+	jwtAudience   string
+	jwtKey        string
+	proxyAuthUser string
+	proxyAuthPass string
+	jwksKeyFunc   keyfunc.Keyfunc
+)
+
+func initAuth() {
+	authMethod = strings.ToLower(os.Getenv("AUTH_METHOD"))
+	if authMethod == "" {
+		authMethod = "none"
+	}
+	jwtJWKSURL = os.Getenv("JWT_JWKS_URL")
+	jwtIssuer = os.Getenv("JWT_ISSUER")
+	jwtAudience = os.Getenv("JWT_AUDIENCE")
+	jwtKey = os.Getenv("JWT_KEY")
+	proxyAuthUser = os.Getenv("PROXY_AUTH_USER")
+	proxyAuthPass = os.Getenv("PROXY_AUTH_PASS")
+
+	switch authMethod {
+	case "none":
+		log.Println("🔓 AUTH_METHOD=none: собственные эндпоинты прокси не защищены")
+	case "basic":
+		if proxyAuthUser == "" || proxyAuthPass == "" {
+			log.Fatal("Ошибка: для AUTH_METHOD=basic нужно установить PROXY_AUTH_USER и PROXY_AUTH_PASS")
+		}
+		log.Println("🔐 AUTH_METHOD=basic: включена Basic Auth для эндпоинтов прокси")
+	case "jwt":
+		if jwtJWKSURL == "" && jwtKey == "" {
+			log.Fatal("Ошибка: для AUTH_METHOD=jwt нужно установить либо JWT_JWKS_URL, либо JWT_KEY")
+		}
+		if jwtJWKSURL != "" {
+			kf, err := keyfunc.NewDefaultCtx(context.Background(), []string{jwtJWKSURL})
+			if err != nil {
+				log.Fatalf("Ошибка загрузки JWKS с %s: %v", jwtJWKSURL, err)
+			}
+			jwksKeyFunc = kf
+		}
+		log.Println("🔐 AUTH_METHOD=jwt: включена проверка JWT bearer-токенов")
+	default:
+		log.Fatalf("Ошибка: неизвестный AUTH_METHOD=%q (ожидается none, basic или jwt)", authMethod)
+	}
+}
+
+// requireAuth оборачивает обработчик, применяя метод аутентификации,
+// выбранный в AUTH_METHOD, и кладёт извлечённые Claims в контекст запроса.
+func requireAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch authMethod {
+		case "basic":
+			user, pass, ok := r.BasicAuth()
+			if !ok || user != proxyAuthUser || pass != proxyAuthPass {
+				w.Header().Set("WWW-Authenticate", `Basic realm="monlightProxy"`)
+				sendError(w, http.StatusUnauthorized, "Неверные или отсутствующие учётные данные")
+				return
+			}
+			next(w, r.WithContext(context.WithValue(r.Context(), claimsContextKey, &Claims{User: user})))
+		case "jwt":
+			claims, err := parseBearerToken(r)
+			if err != nil {
+				sendError(w, http.StatusUnauthorized, fmt.Sprintf("Ошибка проверки токена: %v", err))
+				return
+			}
+			next(w, r.WithContext(context.WithValue(r.Context(), claimsContextKey, claims)))
+		default:
+			next(w, r)
+		}
+	}
+}
+
+func parseBearerToken(r *http.Request) (*Claims, error) {
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") {
+		return nil, fmt.Errorf("отсутствует заголовок Authorization: Bearer <token>")
+	}
+	rawToken := strings.TrimPrefix(header, "Bearer ")
+
+	keyFunc := jwtKeyFunc()
+	var opts []jwt.ParserOption
+	if jwtIssuer != "" {
+		opts = append(opts, jwt.WithIssuer(jwtIssuer))
+	}
+	if jwtAudience != "" {
+		opts = append(opts, jwt.WithAudience(jwtAudience))
+	}
+	token, err := jwt.Parse(rawToken, keyFunc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, fmt.Errorf("токен недействителен")
+	}
+
+	mapClaims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, fmt.Errorf("неожиданный формат claims")
+	}
+
+	claims := &Claims{}
+	if u, ok := mapClaims["user"].(string); ok {
+		claims.User = u
+	}
+	claims.Actions = stringSliceClaim(mapClaims, "actions")
+	claims.Tunnels = stringSliceClaim(mapClaims, "tunnels")
+
+	return claims, nil
+}
+
+func stringSliceClaim(claims jwt.MapClaims, name string) []string {
+	raw, ok := claims[name].([]interface{})
+	if !ok {
+		return nil
+	}
+	out := make([]string, 0, len(raw))
+	for _, v := range raw {
+		if s, ok := v.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// jwtKeyFunc возвращает jwt.Keyfunc либо для JWKS, либо для статического ключа
+// (HMAC-секрет или RSA public key в PEM), в зависимости от того, что настроено.
+func jwtKeyFunc() jwt.Keyfunc {
+	if jwksKeyFunc != nil {
+		return jwksKeyFunc.Keyfunc
+	}
+	return func(token *jwt.Token) (interface{}, error) {
+		if strings.HasPrefix(jwtKey, "-----BEGIN") {
+			key, err := jwt.ParseRSAPublicKeyFromPEM([]byte(jwtKey))
+			if err != nil {
+				return nil, fmt.Errorf("не удалось разобрать JWT_KEY как RSA public key: %v", err)
+			}
+			if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+				return nil, fmt.Errorf("неожиданный метод подписи %v для RSA ключа", token.Method.Alg())
+			}
+			return key, nil
+		}
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("неожиданный метод подписи %v для HMAC ключа", token.Method.Alg())
+		}
+		return []byte(jwtKey), nil
+	}
+}
+
+// claimsFromContext достаёт Claims, помещённые requireAuth. Возвращает nil, если
+// аутентификация отключена (AUTH_METHOD=none).
+func claimsFromContext(ctx context.Context) *Claims {
+	claims, _ := ctx.Value(claimsContextKey).(*Claims)
+	return claims
+}
+
+// allowsTunnel проверяет, разрешено ли вызывающей стороне видеть соединение с
+// данным tunnel/path. Пустой список Tunnels означает доступ ко всем (например,
+// для администраторских токенов).
+func (c *Claims) allowsTunnel(tunnel string) bool {
+	if c == nil || len(c.Tunnels) == 0 {
+		return true
+	}
+	for _, pattern := range c.Tunnels {
+		if ok, err := filepath.Match(pattern, tunnel); ok && err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// allowsAction проверяет, разрешено ли вызывающей стороне выполнять действие
+// с данным именем (например "rtspsessions.kick"). Пустой список Actions, как
+// и у allowsTunnel, означает доступ ко всем действиям.
+func (c *Claims) allowsAction(action string) bool {
+	if c == nil || len(c.Actions) == 0 {
+		return true
+	}
+	for _, a := range c.Actions {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+// requireAction оборачивает обработчик, отклоняя вызов с 403, если Claims,
+// положенные requireAuth в контекст, не разрешают указанное действие.
+func requireAction(action string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := claimsFromContext(r.Context())
+		if !claims.allowsAction(action) {
+			sendError(w, http.StatusForbidden, fmt.Sprintf("Действие %q не разрешено для данного токена", action))
+			return
+		}
+		next(w, r)
+	}
+}
+
+// requireUnrestrictedTunnels отклоняет вызов с 403, если у вызывающей
+// стороны Claims ограничивают видимость конкретными Tunnels. В отличие от
+// /api/connections, обработчики paths/rtspsessions/rtmpconns/webrtcsessions/
+// srtconns и их kick-эндпоинты не знают, как отфильтровать результат
+// MediaMTX по tunnel (сначала отфильтровать, потом пересчитать пагинацию —
+// как это сделано в filterConnectionsForCaller), поэтому они целиком закрыты
+// для tunnel-ограниченных токенов и остаются доступны только токенам без
+// ограничения Tunnels (т.е. административным).
+func requireUnrestrictedTunnels(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := claimsFromContext(r.Context())
+		if claims != nil && len(claims.Tunnels) > 0 {
+			sendError(w, http.StatusForbidden, "этот эндпоинт не поддерживает токены с ограничением Tunnels, нужен токен с полным доступом")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// filterConnectionsForCaller оставляет только те соединения, tunnel которых
+// разрешён переданными Claims. Должна применяться к полному снимку ДО
+// пагинации — иначе pageCount/itemCount продолжат описывать неотфильтрованный
+// набор, а отфильтрованной окажется только уже нарезанная страница.
+func filterConnectionsForCaller(items []RTSConnection, claims *Claims) []RTSConnection {
+	if claims == nil || len(claims.Tunnels) == 0 {
+		return items
+	}
+
+	filtered := make([]RTSConnection, 0, len(items))
+	for _, conn := range items {
+		if claims.allowsTunnel(conn.Tunnel) {
+			filtered = append(filtered, conn)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,174 @@
+// Package main тесты: как и сам прокси при запуске, этот пакет требует
+// CONFIG_FILE либо BASE_URL/AUTH_USER/AUTH_PASS в окружении — без них
+// package-level init() в main.go завершает процесс через log.Fatalf ещё
+// до того, как начнут выполняться тесты.
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/NotJohnPreston/monlightProxy/internal/mediamtx"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestClaimsAllowsTunnel(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims *Claims
+		tunnel string
+		want   bool
+	}{
+		{"nil claims allow everything", nil, "cam-1", true},
+		{"empty Tunnels allow everything", &Claims{}, "cam-1", true},
+		{"exact match", &Claims{Tunnels: []string{"cam-1"}}, "cam-1", true},
+		{"glob match", &Claims{Tunnels: []string{"cam-*"}}, "cam-1", true},
+		{"no match", &Claims{Tunnels: []string{"cam-*"}}, "door-1", false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.claims.allowsTunnel(tc.tunnel); got != tc.want {
+				t.Errorf("allowsTunnel(%q) = %v, want %v", tc.tunnel, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestClaimsAllowsAction(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims *Claims
+		action string
+		want   bool
+	}{
+		{"nil claims allow everything", nil, "rtspsessions.kick", true},
+		{"empty Actions allow everything", &Claims{}, "rtspsessions.kick", true},
+		{"listed action allowed", &Claims{Actions: []string{"rtspsessions.kick"}}, "rtspsessions.kick", true},
+		{"unlisted action denied", &Claims{Actions: []string{"rtspsessions.kick"}}, "config.global.patch", false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.claims.allowsAction(tc.action); got != tc.want {
+				t.Errorf("allowsAction(%q) = %v, want %v", tc.action, got, tc.want)
+			}
+		})
+	}
+}
+
+// TestFilterThenPaginate — регрессионный тест на порядок операций: снимок
+// нужно фильтровать по tunnel ДО пагинации, иначе pageCount/itemCount
+// продолжат описывать неотфильтрованный набор, а отфильтрованной окажется
+// только уже нарезанная страница.
+func TestFilterThenPaginate(t *testing.T) {
+	items := []RTSConnection{
+		{ID: "1", Tunnel: "cam-1"},
+		{ID: "2", Tunnel: "door-1"},
+		{ID: "3", Tunnel: "cam-2"},
+		{ID: "4", Tunnel: "door-2"},
+		{ID: "5", Tunnel: "cam-3"},
+	}
+	claims := &Claims{Tunnels: []string{"cam-*"}}
+
+	filtered := filterConnectionsForCaller(items, claims)
+	resp := paginateConnections(filtered, mediamtx.PageParams{Page: 0, ItemsPerPage: 2})
+
+	if resp.ItemCount != 3 {
+		t.Errorf("ItemCount = %d, want 3 (only cam-* tunnels)", resp.ItemCount)
+	}
+	if resp.PageCount != 2 {
+		t.Errorf("PageCount = %d, want 2 (3 filtered items, 2 per page)", resp.PageCount)
+	}
+	if len(resp.Items) != 2 || resp.Items[0].ID != "1" || resp.Items[1].ID != "3" {
+		t.Errorf("unexpected page items: %+v", resp.Items)
+	}
+}
+
+// signHS256 подписывает HMAC-токен с данными claims — помощник для тестов
+// parseBearerToken, которому не нужен настоящий IdP.
+func signHS256(t *testing.T, secret string, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	signed, err := token.SignedString([]byte(secret))
+	if err != nil {
+		t.Fatalf("ошибка подписи тестового токена: %v", err)
+	}
+	return signed
+}
+
+// withJWTConfig временно подменяет глобальные настройки JWT (как это делает
+// initAuth() из env) и через t.Cleanup восстанавливает исходные значения по
+// завершении теста.
+func withJWTConfig(t *testing.T, key, issuer, audience string) {
+	t.Helper()
+	prevKey, prevIssuer, prevAudience := jwtKey, jwtIssuer, jwtAudience
+	jwtKey, jwtIssuer, jwtAudience = key, issuer, audience
+	t.Cleanup(func() {
+		jwtKey, jwtIssuer, jwtAudience = prevKey, prevIssuer, prevAudience
+	})
+}
+
+// TestParseBearerTokenRoundTrip — регрессионный тест на баг, из-за которого
+// jwt.WithIssuer("")/jwt.WithAudience("") требовали от токена буквально
+// пустые iss/aud вместо пропуска проверки, когда JWT_ISSUER/JWT_AUDIENCE не
+// заданы (для AUTH_METHOD=jwt initAuth не требует ни одну из них).
+func TestParseBearerTokenRoundTrip(t *testing.T) {
+	const secret = "test-secret"
+
+	t.Run("issuer/audience unset accepts a normal token", func(t *testing.T) {
+		withJWTConfig(t, secret, "", "")
+		raw := signHS256(t, secret, jwt.MapClaims{
+			"user":    "alice",
+			"iss":     "https://idp.example.com/",
+			"aud":     "monlight-proxy",
+			"actions": []interface{}{"rtspsessions.kick"},
+			"tunnels": []interface{}{"cam-*"},
+			"exp":     time.Now().Add(time.Hour).Unix(),
+		})
+		req, _ := http.NewRequest(http.MethodGet, "/api/connections", nil)
+		req.Header.Set("Authorization", "Bearer "+raw)
+
+		claims, err := parseBearerToken(req)
+		if err != nil {
+			t.Fatalf("parseBearerToken с незаданными JWT_ISSUER/JWT_AUDIENCE вернул ошибку: %v", err)
+		}
+		if claims.User != "alice" {
+			t.Errorf("User = %q, want alice", claims.User)
+		}
+		if len(claims.Actions) != 1 || claims.Actions[0] != "rtspsessions.kick" {
+			t.Errorf("Actions = %v, want [rtspsessions.kick]", claims.Actions)
+		}
+		if len(claims.Tunnels) != 1 || claims.Tunnels[0] != "cam-*" {
+			t.Errorf("Tunnels = %v, want [cam-*]", claims.Tunnels)
+		}
+	})
+
+	t.Run("issuer/audience set enforces them", func(t *testing.T) {
+		withJWTConfig(t, secret, "https://idp.example.com/", "monlight-proxy")
+		raw := signHS256(t, secret, jwt.MapClaims{
+			"user": "alice",
+			"iss":  "https://idp.example.com/",
+			"aud":  "monlight-proxy",
+			"exp":  time.Now().Add(time.Hour).Unix(),
+		})
+		req, _ := http.NewRequest(http.MethodGet, "/api/connections", nil)
+		req.Header.Set("Authorization", "Bearer "+raw)
+
+		if _, err := parseBearerToken(req); err != nil {
+			t.Fatalf("parseBearerToken с совпадающими iss/aud вернул ошибку: %v", err)
+		}
+
+		raw = signHS256(t, secret, jwt.MapClaims{
+			"user": "alice",
+			"iss":  "https://someone-else.example.com/",
+			"aud":  "monlight-proxy",
+			"exp":  time.Now().Add(time.Hour).Unix(),
+		})
+		req, _ = http.NewRequest(http.MethodGet, "/api/connections", nil)
+		req.Header.Set("Authorization", "Bearer "+raw)
+
+		if _, err := parseBearerToken(req); err == nil {
+			t.Error("parseBearerToken с неверным iss должен вернуть ошибку, когда JWT_ISSUER задан")
+		}
+	})
+}
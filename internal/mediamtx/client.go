@@ -0,0 +1,146 @@
+// Package mediamtx содержит клиент для MediaMTX v3 API, используемый прокси
+// вместо того, чтобы каждый обработчик сам собирал запросы с Basic Auth.
+package mediamtx
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"time"
+)
+
+// Client инкапсулирует соединение с одним инстансом MediaMTX: базовый URL,
+// credentials для Basic Auth, общий cookie jar и политику повторных попыток.
+type Client struct {
+	BaseURL    string
+	AuthUser   string
+	AuthPass   string
+	httpClient *http.Client
+	maxRetries int
+	retryDelay time.Duration
+}
+
+// NewClient создаёт клиента с cookie jar и таймаутом, аналогичными тем, что
+// раньше собирались вручную в getAuthenticatedClient.
+func NewClient(baseURL, authUser, authPass string) (*Client, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка создания cookie jar: %v", err)
+	}
+
+	return &Client{
+		BaseURL:  baseURL,
+		AuthUser: authUser,
+		AuthPass: authPass,
+		httpClient: &http.Client{
+			Jar:     jar,
+			Timeout: 30 * time.Second,
+		},
+		maxRetries: 2,
+		retryDelay: 200 * time.Millisecond,
+	}, nil
+}
+
+// SetTransport заменяет http.RoundTripper, используемый клиентом — нужен
+// вызывающему коду, чтобы обернуть исходящие запросы инструментацией
+// (метрики, трассировка) без дублирования остальной логики Client.
+func (c *Client) SetTransport(rt http.RoundTripper) {
+	c.httpClient.Transport = rt
+}
+
+// HTTPClient возвращает обёрнутый http.Client — нужен коду, которому требуется
+// выполнить запрос вручную (например, диагностическим обработчикам).
+func (c *Client) HTTPClient() *http.Client {
+	return c.httpClient
+}
+
+// PageParams — общие параметры постраничной выдачи, которые принимает почти
+// каждый list-эндпоинт MediaMTX.
+type PageParams struct {
+	Page         int
+	ItemsPerPage int
+}
+
+func (p PageParams) query() url.Values {
+	q := url.Values{}
+	q.Set("page", fmt.Sprintf("%d", p.Page))
+	q.Set("itemsPerPage", fmt.Sprintf("%d", p.ItemsPerPage))
+	return q
+}
+
+// get выполняет GET-запрос к path на базовом URL, добавляет Basic Auth и
+// повторяет запрос при сетевых ошибках или 5xx-ответах.
+func (c *Client) get(path string, query url.Values) ([]byte, int, error) {
+	return c.do(http.MethodGet, path, query, nil)
+}
+
+// post выполняет POST-запрос (используется kick-эндпоинтами и patch конфига).
+func (c *Client) post(path string, query url.Values, body interface{}) ([]byte, int, error) {
+	var encoded []byte
+	if body != nil {
+		var err error
+		encoded, err = json.Marshal(body)
+		if err != nil {
+			return nil, 0, fmt.Errorf("ошибка кодирования тела запроса: %v", err)
+		}
+	}
+	return c.do(http.MethodPost, path, query, encoded)
+}
+
+// do выполняет запрос и повторяет его при сетевых ошибках или 5xx-ответах.
+// body передаётся как []byte, а не io.Reader, потому что http.Request читает
+// (и тем самым опустошает) тело на первой же попытке — из среза же новый
+// bytes.NewReader собирается заново на каждый attempt.
+func (c *Client) do(method, path string, query url.Values, body []byte) ([]byte, int, error) {
+	fullURL := c.BaseURL + path
+	if len(query) > 0 {
+		fullURL += "?" + query.Encode()
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= c.maxRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(c.retryDelay * time.Duration(attempt))
+		}
+
+		var reqBody io.Reader
+		if body != nil {
+			reqBody = bytes.NewReader(body)
+		}
+		req, err := http.NewRequest(method, fullURL, reqBody)
+		if err != nil {
+			return nil, 0, fmt.Errorf("ошибка создания запроса: %v", err)
+		}
+		req.SetBasicAuth(c.AuthUser, c.AuthPass)
+		req.Header.Set("Accept", "application/json")
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			lastErr = fmt.Errorf("ошибка запроса к %s: %v", path, err)
+			continue
+		}
+
+		respBody, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			lastErr = fmt.Errorf("ошибка чтения ответа от %s: %v", path, err)
+			continue
+		}
+
+		if resp.StatusCode >= 500 {
+			lastErr = fmt.Errorf("%s вернул статус %d: %s", path, resp.StatusCode, string(respBody))
+			continue
+		}
+
+		return respBody, resp.StatusCode, nil
+	}
+
+	return nil, 0, lastErr
+}
@@ -0,0 +1,173 @@
+package mediamtx
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+)
+
+// ListPaths вызывает GET /api/v3/paths/list.
+func (c *Client) ListPaths(p PageParams) (PathsListResponse, error) {
+	var out PathsListResponse
+	body, status, err := c.get("/api/v3/paths/list", p.query())
+	if err != nil {
+		return out, err
+	}
+	if status != 200 {
+		return out, fmt.Errorf("paths/list вернул статус %d", status)
+	}
+	return out, json.Unmarshal(body, &out)
+}
+
+// GetPath вызывает GET /api/v3/paths/get/{name}.
+func (c *Client) GetPath(name string) (PathConf, error) {
+	var out PathConf
+	body, status, err := c.get("/api/v3/paths/get/"+name, nil)
+	if err != nil {
+		return out, err
+	}
+	if status != 200 {
+		return out, fmt.Errorf("paths/get/%s вернул статус %d", name, status)
+	}
+	return out, json.Unmarshal(body, &out)
+}
+
+// ListRTSPSessions вызывает GET /api/v3/rtspsessions/list.
+func (c *Client) ListRTSPSessions(p PageParams) (RTSPSessionsListResponse, error) {
+	var out RTSPSessionsListResponse
+	body, status, err := c.get("/api/v3/rtspsessions/list", p.query())
+	if err != nil {
+		return out, err
+	}
+	if status != 200 {
+		return out, fmt.Errorf("rtspsessions/list вернул статус %d", status)
+	}
+	return out, json.Unmarshal(body, &out)
+}
+
+// KickRTSPSession вызывает POST /api/v3/rtspsessions/kick/{id}.
+func (c *Client) KickRTSPSession(id string) error {
+	return c.kick("/api/v3/rtspsessions/kick/" + id)
+}
+
+// ListRTMPConns вызывает GET /api/v3/rtmpconns/list.
+func (c *Client) ListRTMPConns(p PageParams) (RTMPConnsListResponse, error) {
+	var out RTMPConnsListResponse
+	body, status, err := c.get("/api/v3/rtmpconns/list", p.query())
+	if err != nil {
+		return out, err
+	}
+	if status != 200 {
+		return out, fmt.Errorf("rtmpconns/list вернул статус %d", status)
+	}
+	return out, json.Unmarshal(body, &out)
+}
+
+// KickRTMPConn вызывает POST /api/v3/rtmpconns/kick/{id}.
+func (c *Client) KickRTMPConn(id string) error {
+	return c.kick("/api/v3/rtmpconns/kick/" + id)
+}
+
+// ListWebRTCSessions вызывает GET /api/v3/webrtcsessions/list.
+func (c *Client) ListWebRTCSessions(p PageParams) (WebRTCSessionsListResponse, error) {
+	var out WebRTCSessionsListResponse
+	body, status, err := c.get("/api/v3/webrtcsessions/list", p.query())
+	if err != nil {
+		return out, err
+	}
+	if status != 200 {
+		return out, fmt.Errorf("webrtcsessions/list вернул статус %d", status)
+	}
+	return out, json.Unmarshal(body, &out)
+}
+
+// KickWebRTCSession вызывает POST /api/v3/webrtcsessions/kick/{id}.
+func (c *Client) KickWebRTCSession(id string) error {
+	return c.kick("/api/v3/webrtcsessions/kick/" + id)
+}
+
+// ListSRTConns вызывает GET /api/v3/srtconns/list.
+func (c *Client) ListSRTConns(p PageParams) (SRTConnsListResponse, error) {
+	var out SRTConnsListResponse
+	body, status, err := c.get("/api/v3/srtconns/list", p.query())
+	if err != nil {
+		return out, err
+	}
+	if status != 200 {
+		return out, fmt.Errorf("srtconns/list вернул статус %d", status)
+	}
+	return out, json.Unmarshal(body, &out)
+}
+
+// KickSRTConn вызывает POST /api/v3/srtconns/kick/{id}.
+func (c *Client) KickSRTConn(id string) error {
+	return c.kick("/api/v3/srtconns/kick/" + id)
+}
+
+// ListHLSMuxers вызывает GET /api/v3/hlsmuxers/list.
+func (c *Client) ListHLSMuxers(p PageParams) (HLSMuxersListResponse, error) {
+	var out HLSMuxersListResponse
+	body, status, err := c.get("/api/v3/hlsmuxers/list", p.query())
+	if err != nil {
+		return out, err
+	}
+	if status != 200 {
+		return out, fmt.Errorf("hlsmuxers/list вернул статус %d", status)
+	}
+	return out, json.Unmarshal(body, &out)
+}
+
+// ListRecordings вызывает GET /api/v3/recordings/list.
+func (c *Client) ListRecordings(p PageParams) (RecordingsListResponse, error) {
+	var out RecordingsListResponse
+	body, status, err := c.get("/api/v3/recordings/list", p.query())
+	if err != nil {
+		return out, err
+	}
+	if status != 200 {
+		return out, fmt.Errorf("recordings/list вернул статус %d", status)
+	}
+	return out, json.Unmarshal(body, &out)
+}
+
+// GetGlobalConfig вызывает GET /api/v3/config/global/get.
+func (c *Client) GetGlobalConfig() (GlobalConfig, error) {
+	var out GlobalConfig
+	body, status, err := c.get("/api/v3/config/global/get", nil)
+	if err != nil {
+		return out, err
+	}
+	if status != 200 {
+		return out, fmt.Errorf("config/global/get вернул статус %d", status)
+	}
+	return out, json.Unmarshal(body, &out)
+}
+
+// PatchGlobalConfig вызывает POST /api/v3/config/global/patch с частичным конфигом.
+func (c *Client) PatchGlobalConfig(patch map[string]interface{}) error {
+	_, status, err := c.post("/api/v3/config/global/patch", nil, patch)
+	if err != nil {
+		return err
+	}
+	if status < 200 || status >= 300 {
+		return fmt.Errorf("config/global/patch вернул статус %d", status)
+	}
+	return nil
+}
+
+func (c *Client) kick(path string) error {
+	_, status, err := c.post(path, url.Values{}, nil)
+	if err != nil {
+		return err
+	}
+	if status < 200 || status >= 300 {
+		return fmt.Errorf("%s вернул статус %d", path, status)
+	}
+	return nil
+}
+
+// ListConnections вызывает GET /api/v3/rtspconns/list — исторический эндпоинт,
+// который прокси оборачивал до появления остальных ресурсов.
+func (c *Client) ListConnections(p PageParams) ([]byte, int, error) {
+	return c.get("/api/v3/rtspconns/list", p.query())
+}
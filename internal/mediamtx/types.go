@@ -0,0 +1,127 @@
+package mediamtx
+
+// PathConf описывает настройки одного пути (path) MediaMTX, как их возвращает
+// paths/get и paths/list.
+type PathConf struct {
+	Name     string   `json:"name"`
+	ConfName string   `json:"confName"`
+	Source   string   `json:"source"`
+	Ready    bool     `json:"ready"`
+	Tracks   []string `json:"tracks"`
+}
+
+type PathsListResponse struct {
+	PageCount int        `json:"pageCount"`
+	ItemCount int        `json:"itemCount"`
+	Items     []PathConf `json:"items"`
+}
+
+// RTSPSession — активная RTSP-сессия (в отличие от rtspconns, которая отражает
+// только TCP-соединение).
+type RTSPSession struct {
+	ID            string `json:"id"`
+	Created       string `json:"created"`
+	RemoteAddr    string `json:"remoteAddr"`
+	State         string `json:"state"`
+	Path          string `json:"path"`
+	BytesReceived int64  `json:"bytesReceived"`
+	BytesSent     int64  `json:"bytesSent"`
+}
+
+type RTSPSessionsListResponse struct {
+	PageCount int           `json:"pageCount"`
+	ItemCount int           `json:"itemCount"`
+	Items     []RTSPSession `json:"items"`
+}
+
+// RTMPConn — соединение RTMP-сервера.
+type RTMPConn struct {
+	ID            string `json:"id"`
+	Created       string `json:"created"`
+	RemoteAddr    string `json:"remoteAddr"`
+	State         string `json:"state"`
+	Path          string `json:"path"`
+	BytesReceived int64  `json:"bytesReceived"`
+	BytesSent     int64  `json:"bytesSent"`
+}
+
+type RTMPConnsListResponse struct {
+	PageCount int        `json:"pageCount"`
+	ItemCount int        `json:"itemCount"`
+	Items     []RTMPConn `json:"items"`
+}
+
+// WebRTCSession — сессия WebRTC-сервера.
+type WebRTCSession struct {
+	ID            string `json:"id"`
+	Created       string `json:"created"`
+	RemoteAddr    string `json:"remoteAddr"`
+	State         string `json:"state"`
+	Path          string `json:"path"`
+	BytesReceived int64  `json:"bytesReceived"`
+	BytesSent     int64  `json:"bytesSent"`
+}
+
+type WebRTCSessionsListResponse struct {
+	PageCount int             `json:"pageCount"`
+	ItemCount int             `json:"itemCount"`
+	Items     []WebRTCSession `json:"items"`
+}
+
+// SRTConn — соединение SRT-сервера.
+type SRTConn struct {
+	ID            string `json:"id"`
+	Created       string `json:"created"`
+	RemoteAddr    string `json:"remoteAddr"`
+	State         string `json:"state"`
+	Path          string `json:"path"`
+	BytesReceived int64  `json:"bytesReceived"`
+	BytesSent     int64  `json:"bytesSent"`
+}
+
+type SRTConnsListResponse struct {
+	PageCount int       `json:"pageCount"`
+	ItemCount int       `json:"itemCount"`
+	Items     []SRTConn `json:"items"`
+}
+
+// HLSMuxer — активный HLS-мьюксер, обслуживающий один путь.
+type HLSMuxer struct {
+	Path        string `json:"path"`
+	Created     string `json:"created"`
+	LastRequest string `json:"lastRequest"`
+}
+
+type HLSMuxersListResponse struct {
+	PageCount int        `json:"pageCount"`
+	ItemCount int        `json:"itemCount"`
+	Items     []HLSMuxer `json:"items"`
+}
+
+// Recording — запись, сохранённая MediaMTX для определённого пути.
+type Recording struct {
+	Name     string             `json:"name"`
+	Segments []RecordingSegment `json:"segments"`
+}
+
+type RecordingSegment struct {
+	Start string `json:"start"`
+}
+
+type RecordingsListResponse struct {
+	PageCount int         `json:"pageCount"`
+	ItemCount int         `json:"itemCount"`
+	Items     []Recording `json:"items"`
+}
+
+// GlobalConfig — усечённое отражение config/global/get: прокси не нужно знать
+// обо всех полях, только о тех, что он готов показывать/патчить.
+type GlobalConfig struct {
+	LogLevel string `json:"logLevel"`
+	API      bool   `json:"api"`
+	RTSP     bool   `json:"rtsp"`
+	RTMP     bool   `json:"rtmp"`
+	WebRTC   bool   `json:"webrtc"`
+	SRT      bool   `json:"srt"`
+	HLS      bool   `json:"hls"`
+}
@@ -0,0 +1,90 @@
+package observability
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+)
+
+type contextKey string
+
+const requestIDContextKey contextKey = "requestID"
+
+// NewLogger строит slog.Logger, формат и уровень которого заданы
+// LOG_FORMAT (json|text, по умолчанию json) и LOG_LEVEL (debug/info/warn/error,
+// по умолчанию info).
+func NewLogger() *slog.Logger {
+	level := parseLevel(os.Getenv("LOG_LEVEL"))
+	format := strings.ToLower(os.Getenv("LOG_FORMAT"))
+
+	opts := &slog.HandlerOptions{Level: level}
+
+	var handler slog.Handler
+	if format == "text" {
+		handler = slog.NewTextHandler(os.Stdout, opts)
+	} else {
+		handler = slog.NewJSONHandler(os.Stdout, opts)
+	}
+
+	return slog.New(handler)
+}
+
+func parseLevel(raw string) slog.Level {
+	switch strings.ToLower(raw) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// WithRequestID кладёт request id в контекст, чтобы его можно было прикрепить
+// к каждой лог-записи, сделанной в рамках обработки запроса.
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey, requestID)
+}
+
+// RequestIDFromContext достаёт request id, помещённый RequestIDMiddleware.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDContextKey).(string)
+	return id
+}
+
+// LoggerWithRequestID возвращает logger с полем request_id, извлечённым из
+// контекста запроса — для использования внутри обработчиков.
+func LoggerWithRequestID(logger *slog.Logger, ctx context.Context) *slog.Logger {
+	if id := RequestIDFromContext(ctx); id != "" {
+		return logger.With("request_id", id)
+	}
+	return logger
+}
+
+// RequestIDMiddleware читает X-Request-ID из входящего запроса (или
+// генерирует новый), кладёт его в контекст и отражает в заголовке ответа —
+// так клиент и сервер ссылаются на одну и ту же лог-запись.
+func RequestIDMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get("X-Request-ID")
+		if requestID == "" {
+			requestID = generateRequestID()
+		}
+		w.Header().Set("X-Request-ID", requestID)
+		next(w, r.WithContext(WithRequestID(r.Context(), requestID)))
+	}
+}
+
+func generateRequestID() string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "unknown"
+	}
+	return fmt.Sprintf("%x", buf)
+}
@@ -0,0 +1,202 @@
+// Package observability собирает Prometheus-метрики и структурированные логи
+// для monlightProxy: инструментирует исходящие запросы к MediaMTX и входящие
+// запросы к самому прокси.
+package observability
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	upstreamRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "upstream_request_duration_seconds",
+		Help:    "Длительность запросов прокси к upstream MediaMTX.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"endpoint", "status"})
+
+	upstreamBytesIn = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "upstream_bytes_in_total",
+		Help: "Байты, полученные от upstream MediaMTX.",
+	}, []string{"endpoint"})
+
+	upstreamBytesOut = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "upstream_bytes_out_total",
+		Help: "Байты, отправленные в upstream MediaMTX.",
+	}, []string{"endpoint"})
+
+	cachedClientAge = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "upstream_cached_client_age_seconds",
+		Help: "Возраст закэшированного HTTP-клиента к MediaMTX.",
+	})
+
+	inboundRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "proxy_http_requests_total",
+		Help: "Количество запросов к собственным эндпоинтам прокси.",
+	}, []string{"path", "method", "status"})
+
+	inboundRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "proxy_http_request_duration_seconds",
+		Help:    "Длительность обработки запросов к собственным эндпоинтам прокси.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"path", "method"})
+
+	inboundRequestsInFlight = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "proxy_http_requests_in_flight",
+		Help: "Количество запросов к прокси, обрабатываемых прямо сейчас.",
+	}, []string{"path"})
+)
+
+func init() {
+	prometheus.MustRegister(
+		upstreamRequestDuration,
+		upstreamBytesIn,
+		upstreamBytesOut,
+		cachedClientAge,
+		inboundRequestsTotal,
+		inboundRequestDuration,
+		inboundRequestsInFlight,
+	)
+}
+
+// MetricsHandler отдаёт метрики в текстовом формате Prometheus.
+func MetricsHandler() http.Handler {
+	return promhttp.Handler()
+}
+
+// SetCachedClientAge обновляет gauge возраста закэшированного клиента к MediaMTX.
+func SetCachedClientAge(since time.Time) {
+	cachedClientAge.Set(time.Since(since).Seconds())
+}
+
+// InstrumentedTransport оборачивает http.RoundTripper и пишет метрики по
+// каждому исходящему запросу к MediaMTX: длительность, статус, байты.
+type InstrumentedTransport struct {
+	Base http.RoundTripper
+}
+
+func (t *InstrumentedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	endpoint := normalizeEndpoint(req.URL.Path)
+	reqBytes := req.ContentLength
+	if reqBytes < 0 {
+		reqBytes = 0
+	}
+	upstreamBytesOut.WithLabelValues(endpoint).Add(float64(reqBytes))
+
+	start := time.Now()
+	resp, err := base.RoundTrip(req)
+	duration := time.Since(start).Seconds()
+
+	if err != nil {
+		upstreamRequestDuration.WithLabelValues(endpoint, "error").Observe(duration)
+		return resp, err
+	}
+
+	upstreamRequestDuration.WithLabelValues(endpoint, strconv.Itoa(resp.StatusCode)).Observe(duration)
+	resp.Body = &countingReadCloser{
+		ReadCloser: resp.Body,
+		onClose: func(n int64) {
+			upstreamBytesIn.WithLabelValues(endpoint).Add(float64(n))
+		},
+	}
+
+	return resp, nil
+}
+
+// normalizeEndpoint схлопывает путь upstream-запроса до шаблона маршрута
+// (например "/api/v3/rtspsessions/kick/abc123" -> "rtspsessions/kick"),
+// отбрасывая live id сессии/соединения или имя path из "get/{name}" и
+// "kick/{id}" — иначе каждая сессия заводила бы собственное значение label
+// и метрика росла бы без ограничения по кардинальности.
+func normalizeEndpoint(path string) string {
+	trimmed := strings.Trim(strings.TrimPrefix(path, "/api/v3/"), "/")
+	segments := strings.Split(trimmed, "/")
+	if len(segments) >= 2 {
+		prev := segments[len(segments)-2]
+		if prev == "kick" || prev == "get" {
+			segments = segments[:len(segments)-1]
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// countingReadCloser подсчитывает байты, реально прочитанные из тела ответа,
+// и сообщает итог через onClose при закрытии (io.ReadAll читает до EOF перед
+// вызовом Close, так что счётчик к этому моменту уже финальный).
+type countingReadCloser struct {
+	io.ReadCloser
+	n       int64
+	onClose func(n int64)
+}
+
+func (c *countingReadCloser) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func (c *countingReadCloser) Close() error {
+	c.onClose(c.n)
+	return c.ReadCloser.Close()
+}
+
+// InboundMiddleware инструментирует входящие запросы к собственным
+// эндпоинтам прокси: счётчик, гистограмма длительности, in-flight gauge.
+func InboundMiddleware(path string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		inboundRequestsInFlight.WithLabelValues(path).Inc()
+		defer inboundRequestsInFlight.WithLabelValues(path).Dec()
+
+		start := time.Now()
+		sw := &statusRecordingWriter{ResponseWriter: w, status: http.StatusOK}
+
+		next(sw, r)
+
+		inboundRequestDuration.WithLabelValues(path, r.Method).Observe(time.Since(start).Seconds())
+		inboundRequestsTotal.WithLabelValues(path, r.Method, strconv.Itoa(sw.status)).Inc()
+	}
+}
+
+type statusRecordingWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusRecordingWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Flush проксирует http.Flusher, если его реализует нижележащий
+// ResponseWriter — без этого SSE-эндпоинты (connectionsStreamHandler) отвечают
+// ошибкой "Стриминг не поддерживается" на первом же w.(http.Flusher) под этой middleware.
+func (w *statusRecordingWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack проксирует http.Hijacker, если его реализует нижележащий
+// ResponseWriter — без этого апгрейд WebSocket-соединения (gorilla/websocket)
+// под этой middleware завершается ошибкой "response does not implement http.Hijacker".
+func (w *statusRecordingWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("underlying ResponseWriter does not implement http.Hijacker")
+	}
+	return h.Hijack()
+}
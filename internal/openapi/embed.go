@@ -0,0 +1,9 @@
+// Package openapi парсит встроенную спецификацию monlightProxy и умеет
+// генерировать мок-ответы прямо из неё, вместо того чтобы каждый ресурс
+// поддерживал свой собственный генератор тестовых данных.
+package openapi
+
+import _ "embed"
+
+//go:embed spec.yaml
+var SpecYAML []byte
@@ -0,0 +1,292 @@
+package openapi
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pb33f/libopenapi"
+	"github.com/pb33f/libopenapi/datamodel/high/base"
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+	"gopkg.in/yaml.v3"
+)
+
+// Engine хранит разобранную OpenAPI модель и генерирует мок-ответы на её основе.
+type Engine struct {
+	doc   libopenapi.Document
+	model *v3.Document
+}
+
+// NewEngine разбирает spec (обычно openapi.SpecYAML) и строит v3-модель.
+func NewEngine(spec []byte) (*Engine, error) {
+	doc, err := libopenapi.NewDocument(spec)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка разбора OpenAPI спецификации: %v", err)
+	}
+
+	model, errs := doc.BuildV3Model()
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("ошибка построения модели OpenAPI: %v", errs[0])
+	}
+
+	return &Engine{doc: doc, model: &model.Model}, nil
+}
+
+// Mock — сгенерированный мок-ответ: статус-код и тело в виде Go-значения
+// (map/slice/примитивы), готового к json.Marshal.
+type Mock struct {
+	Status int
+	Body   interface{}
+}
+
+// Generate находит операцию по методу и пути (с поддержкой шаблонов вида
+// {id}) и строит для неё ответ, учитывая заголовки Prefer: example=<name> и
+// Prefer: code=<status>.
+func (e *Engine) Generate(method, path string, header http.Header) (Mock, error) {
+	op, pathParams, err := e.findOperation(method, path)
+	if err != nil {
+		return Mock{}, err
+	}
+
+	preferExample, preferCode := parsePrefer(header.Get("Prefer"))
+
+	status, resp, err := e.selectResponse(op, preferCode)
+	if err != nil {
+		return Mock{}, err
+	}
+
+	if resp.Content == nil {
+		return Mock{Status: status, Body: map[string]interface{}{}}, nil
+	}
+
+	media, ok := resp.Content.Get("application/json")
+	if !ok || media.Schema == nil {
+		return Mock{Status: status, Body: map[string]interface{}{}}, nil
+	}
+
+	schema := media.Schema.Schema()
+
+	if preferExample != "" && media.Examples != nil {
+		if ex, ok := media.Examples.Get(preferExample); ok && ex.Value != nil {
+			return Mock{Status: status, Body: decodeYAMLNode(ex.Value)}, nil
+		}
+	}
+
+	body := generateFromSchema(schema, pathParams)
+	if err := validateAgainstSchema(schema, body); err != nil {
+		return Mock{}, fmt.Errorf("сгенерированный мок не прошёл самопроверку по схеме: %v", err)
+	}
+	return Mock{Status: status, Body: body}, nil
+}
+
+func (e *Engine) findOperation(method, path string) (*v3.Operation, map[string]string, error) {
+	if e.model.Paths == nil || e.model.Paths.PathItems == nil {
+		return nil, nil, fmt.Errorf("спецификация не содержит путей")
+	}
+
+	for pair := e.model.Paths.PathItems.First(); pair != nil; pair = pair.Next() {
+		pattern := pair.Key()
+		item := pair.Value()
+
+		params, ok := matchPath(pattern, path)
+		if !ok {
+			continue
+		}
+
+		op := operationForMethod(item, method)
+		if op == nil {
+			continue
+		}
+
+		return op, params, nil
+	}
+
+	return nil, nil, fmt.Errorf("операция %s %s не описана в OpenAPI спецификации", method, path)
+}
+
+func operationForMethod(item *v3.PathItem, method string) *v3.Operation {
+	switch strings.ToUpper(method) {
+	case http.MethodGet:
+		return item.Get
+	case http.MethodPost:
+		return item.Post
+	case http.MethodPut:
+		return item.Put
+	case http.MethodDelete:
+		return item.Delete
+	case http.MethodPatch:
+		return item.Patch
+	default:
+		return nil
+	}
+}
+
+// matchPath сопоставляет OpenAPI-шаблон пути (например /api/paths/{name}) с
+// реальным путём запроса и возвращает извлечённые параметры.
+func matchPath(pattern, path string) (map[string]string, bool) {
+	patternParts := strings.Split(strings.Trim(pattern, "/"), "/")
+	pathParts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(patternParts) != len(pathParts) {
+		return nil, false
+	}
+
+	params := map[string]string{}
+	for i, pp := range patternParts {
+		if strings.HasPrefix(pp, "{") && strings.HasSuffix(pp, "}") {
+			params[strings.Trim(pp, "{}")] = pathParts[i]
+			continue
+		}
+		if pp != pathParts[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+// decodeYAMLNode разворачивает *yaml.Node (в котором libopenapi хранит
+// example/examples как есть) в обычное Go-значение, пригодное для json.Marshal.
+func decodeYAMLNode(node *yaml.Node) interface{} {
+	if node == nil {
+		return nil
+	}
+	var v interface{}
+	if err := node.Decode(&v); err != nil {
+		return nil
+	}
+	return v
+}
+
+// parsePrefer разбирает заголовок Prefer (RFC 7240): отдельные preference'ы
+// разделены запятой, а точка с запятой отделяет параметры preference'а от
+// него самого (например "code=500;foo=bar, example=populated").
+func parsePrefer(header string) (example string, code int) {
+	for _, pref := range strings.Split(header, ",") {
+		nameValue := strings.SplitN(strings.TrimSpace(pref), ";", 2)[0]
+		kv := strings.SplitN(strings.TrimSpace(nameValue), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		key := strings.TrimSpace(kv[0])
+		val := strings.Trim(strings.TrimSpace(kv[1]), `"`)
+		switch key {
+		case "example":
+			example = val
+		case "code":
+			if c, err := strconv.Atoi(val); err == nil {
+				code = c
+			}
+		}
+	}
+	return example, code
+}
+
+func (e *Engine) selectResponse(op *v3.Operation, preferCode int) (int, *v3.Response, error) {
+	if op.Responses == nil || op.Responses.Codes == nil {
+		return 0, nil, fmt.Errorf("операция не описывает ни одного ответа")
+	}
+
+	if preferCode != 0 {
+		if resp, ok := op.Responses.Codes.Get(strconv.Itoa(preferCode)); ok {
+			return preferCode, resp, nil
+		}
+		return 0, nil, fmt.Errorf("у операции нет описанного ответа %d (Prefer: code)", preferCode)
+	}
+
+	// По умолчанию берём первый описанный 2xx-ответ.
+	for pair := op.Responses.Codes.First(); pair != nil; pair = pair.Next() {
+		code := pair.Key()
+		if strings.HasPrefix(code, "2") {
+			status, _ := strconv.Atoi(code)
+			return status, pair.Value(), nil
+		}
+	}
+
+	for pair := op.Responses.Codes.First(); pair != nil; pair = pair.Next() {
+		status, _ := strconv.Atoi(pair.Key())
+		return status, pair.Value(), nil
+	}
+
+	return 0, nil, fmt.Errorf("операция не описывает ни одного ответа")
+}
+
+// generateFromSchema строит Go-значение из схемы: уважает example/examples,
+// а если их нет — синтезирует значение по type/format. pathParams позволяет
+// подставить реальные значения в сгенерированный объект (например id/name).
+func generateFromSchema(schema *base.Schema, pathParams map[string]string) interface{} {
+	if schema == nil {
+		return nil
+	}
+
+	if schema.Example != nil {
+		return applyPathParams(decodeYAMLNode(schema.Example), pathParams)
+	}
+
+	schemaType := ""
+	if len(schema.Type) > 0 {
+		schemaType = schema.Type[0]
+	}
+
+	switch schemaType {
+	case "object":
+		out := map[string]interface{}{}
+		if schema.Properties != nil {
+			for pair := schema.Properties.First(); pair != nil; pair = pair.Next() {
+				name := pair.Key()
+				propSchema := pair.Value().Schema()
+				if v, ok := pathParams[name]; ok {
+					out[name] = v
+					continue
+				}
+				out[name] = generateFromSchema(propSchema, pathParams)
+			}
+		}
+		return out
+	case "array":
+		if schema.Items == nil || schema.Items.A == nil {
+			return []interface{}{}
+		}
+		itemSchema := schema.Items.A.Schema()
+		return []interface{}{generateFromSchema(itemSchema, pathParams)}
+	case "string":
+		return generateStringValue(schema.Format)
+	case "integer":
+		return 1
+	case "number":
+		return 1.0
+	case "boolean":
+		return true
+	default:
+		return nil
+	}
+}
+
+// applyPathParams подставляет значения из pathParams (реальные id/name из
+// URL) поверх декодированного example — иначе /api/paths/{name} и все
+// *.kick/{id} эндпоинты всегда отвечали бы захардкоженным id/name из
+// spec.yaml вместо эхо запрошенного, даже в MOCK_MODE.
+func applyPathParams(value interface{}, pathParams map[string]string) interface{} {
+	obj, ok := value.(map[string]interface{})
+	if !ok || len(pathParams) == 0 {
+		return value
+	}
+	for name, v := range pathParams {
+		if _, exists := obj[name]; exists {
+			obj[name] = v
+		}
+	}
+	return value
+}
+
+func generateStringValue(format string) string {
+	switch format {
+	case "date-time":
+		return time.Now().UTC().Format(time.RFC3339)
+	case "ipv4":
+		return fmt.Sprintf("203.0.113.%d", rand.Intn(255))
+	default:
+		return "mock"
+	}
+}
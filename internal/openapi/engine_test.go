@@ -0,0 +1,70 @@
+package openapi
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestParsePrefer(t *testing.T) {
+	tests := []struct {
+		name        string
+		header      string
+		wantExample string
+		wantCode    int
+	}{
+		{"empty header", "", "", 0},
+		{"example only", "example=populated", "populated", 0},
+		{"code only", "code=500", "", 500},
+		{"comma-separated preferences", "example=populated, code=500", "populated", 500},
+		{"semicolon params ignored", "code=500;foo=bar", "", 500},
+		{"quoted value", `example="populated"`, "populated", 0},
+		{"whitespace around preferences", " example=populated , code=500 ", "populated", 500},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			example, code := parsePrefer(tc.header)
+			if example != tc.wantExample || code != tc.wantCode {
+				t.Errorf("parsePrefer(%q) = (%q, %d), want (%q, %d)", tc.header, example, code, tc.wantExample, tc.wantCode)
+			}
+		})
+	}
+}
+
+func TestApplyPathParamsOverridesMatchingKeys(t *testing.T) {
+	value := map[string]interface{}{"id": "conn_001", "status": "kicked"}
+
+	got := applyPathParams(value, map[string]string{"id": "xyz999"})
+
+	obj := got.(map[string]interface{})
+	if obj["id"] != "xyz999" {
+		t.Errorf("id = %v, want xyz999", obj["id"])
+	}
+	if obj["status"] != "kicked" {
+		t.Errorf("status = %v, unexpected mutation", obj["status"])
+	}
+}
+
+func TestGenerateEchoesPathParamsOverStaticExample(t *testing.T) {
+	engine, err := NewEngine(SpecYAML)
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+
+	mock, err := engine.Generate(http.MethodPost, "/api/rtspsessions/kick/xyz999", http.Header{})
+	if err != nil {
+		t.Fatalf("Generate(kick): %v", err)
+	}
+	body := mock.Body.(map[string]interface{})
+	if body["id"] != "xyz999" {
+		t.Errorf("kick response id = %v, want xyz999 (the requested id, not the hardcoded example)", body["id"])
+	}
+
+	mock, err = engine.Generate(http.MethodGet, "/api/paths/my-path-name", http.Header{})
+	if err != nil {
+		t.Fatalf("Generate(paths/get): %v", err)
+	}
+	body = mock.Body.(map[string]interface{})
+	if body["name"] != "my-path-name" {
+		t.Errorf("paths/get response name = %v, want my-path-name (the requested name, not the hardcoded example)", body["name"])
+	}
+}
@@ -0,0 +1,81 @@
+package openapi
+
+import (
+	"fmt"
+
+	"github.com/pb33f/libopenapi/datamodel/high/base"
+)
+
+// validateAgainstSchema — самопроверка: прежде чем отдавать сгенерированный
+// мок наружу, Engine убеждается, что то, что он сам построил, действительно
+// соответствует типам, описанным в той же схеме. Это страхует от
+// рассинхронизации между generateFromSchema и spec.yaml при будущих правках.
+func validateAgainstSchema(schema *base.Schema, value interface{}) error {
+	if schema == nil || value == nil {
+		return nil
+	}
+
+	schemaType := ""
+	if len(schema.Type) > 0 {
+		schemaType = schema.Type[0]
+	}
+
+	switch schemaType {
+	case "object":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("ожидался object, получено %T", value)
+		}
+		if schema.Properties == nil {
+			return nil
+		}
+		for pair := schema.Properties.First(); pair != nil; pair = pair.Next() {
+			name := pair.Key()
+			v, present := obj[name]
+			if !present {
+				continue
+			}
+			if err := validateAgainstSchema(pair.Value().Schema(), v); err != nil {
+				return fmt.Errorf("поле %q: %v", name, err)
+			}
+		}
+		return nil
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("ожидался array, получено %T", value)
+		}
+		if schema.Items == nil || schema.Items.A == nil {
+			return nil
+		}
+		itemSchema := schema.Items.A.Schema()
+		for i, item := range items {
+			if err := validateAgainstSchema(itemSchema, item); err != nil {
+				return fmt.Errorf("элемент [%d]: %v", i, err)
+			}
+		}
+		return nil
+	case "string":
+		if _, ok := value.(string); !ok {
+			return fmt.Errorf("ожидалась строка, получено %T", value)
+		}
+	case "integer":
+		switch value.(type) {
+		case int, int64, float64:
+		default:
+			return fmt.Errorf("ожидалось число, получено %T", value)
+		}
+	case "number":
+		switch value.(type) {
+		case int, int64, float64:
+		default:
+			return fmt.Errorf("ожидалось число, получено %T", value)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("ожидался boolean, получено %T", value)
+		}
+	}
+
+	return nil
+}
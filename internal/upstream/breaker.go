@@ -0,0 +1,47 @@
+package upstream
+
+import (
+	"sync"
+	"time"
+)
+
+// CircuitBreaker отключает проблемный upstream после нескольких подряд
+// неудачных запросов, чтобы мёртвый узел не тормозил агрегированные ответы.
+type CircuitBreaker struct {
+	mu               sync.Mutex
+	failureThreshold int
+	tripDuration     time.Duration
+	consecutiveFails int
+	openUntil        time.Time
+}
+
+// NewCircuitBreaker создаёт breaker, открывающийся после failureThreshold
+// подряд идущих ошибок и остающийся открытым tripDuration.
+func NewCircuitBreaker(failureThreshold int, tripDuration time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{failureThreshold: failureThreshold, tripDuration: tripDuration}
+}
+
+// Allow сообщает, можно ли сейчас обращаться к upstream.
+func (b *CircuitBreaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return time.Now().After(b.openUntil)
+}
+
+// RecordSuccess сбрасывает счётчик подряд идущих ошибок.
+func (b *CircuitBreaker) RecordSuccess() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.consecutiveFails = 0
+}
+
+// RecordFailure увеличивает счётчик ошибок и открывает breaker при
+// достижении порога.
+func (b *CircuitBreaker) RecordFailure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.consecutiveFails++
+	if b.consecutiveFails >= b.failureThreshold {
+		b.openUntil = time.Now().Add(b.tripDuration)
+	}
+}
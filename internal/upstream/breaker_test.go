@@ -0,0 +1,52 @@
+package upstream
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerTripsAfterThreshold(t *testing.T) {
+	b := NewCircuitBreaker(3, time.Minute)
+
+	if !b.Allow() {
+		t.Fatal("свежий breaker должен быть закрыт (Allow() == true)")
+	}
+
+	b.RecordFailure()
+	b.RecordFailure()
+	if !b.Allow() {
+		t.Fatal("breaker не должен открываться раньше failureThreshold")
+	}
+
+	b.RecordFailure()
+	if b.Allow() {
+		t.Fatal("breaker должен открыться после failureThreshold подряд идущих ошибок")
+	}
+}
+
+func TestCircuitBreakerResetsOnSuccess(t *testing.T) {
+	b := NewCircuitBreaker(3, time.Minute)
+
+	b.RecordFailure()
+	b.RecordFailure()
+	b.RecordSuccess()
+	b.RecordFailure()
+	b.RecordFailure()
+	if !b.Allow() {
+		t.Fatal("RecordSuccess должен сбрасывать счётчик подряд идущих ошибок")
+	}
+}
+
+func TestCircuitBreakerClosesAfterTripDuration(t *testing.T) {
+	b := NewCircuitBreaker(1, 10*time.Millisecond)
+
+	b.RecordFailure()
+	if b.Allow() {
+		t.Fatal("breaker должен быть открыт сразу после превышения порога")
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if !b.Allow() {
+		t.Fatal("breaker должен закрыться по истечении tripDuration")
+	}
+}
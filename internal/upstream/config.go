@@ -0,0 +1,104 @@
+// Package upstream описывает конфигурацию множественных MediaMTX-инстансов,
+// которые прокси может опрашивать и между которыми распределяет запросы.
+package upstream
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// TLSConfig описывает TLS-настройки соединения с одним upstream'ом.
+type TLSConfig struct {
+	CABundle           string `yaml:"caBundle" json:"caBundle,omitempty"`
+	ClientCert         string `yaml:"clientCert" json:"clientCert,omitempty"`
+	ClientKey          string `yaml:"clientKey" json:"clientKey,omitempty"`
+	InsecureSkipVerify bool   `yaml:"insecureSkipVerify" json:"insecureSkipVerify,omitempty"`
+}
+
+// Upstream — один именованный инстанс MediaMTX.
+type Upstream struct {
+	Name        string    `yaml:"name" json:"name"`
+	BaseURL     string    `yaml:"baseURL" json:"baseURL"`
+	AuthUser    string    `yaml:"authUser" json:"authUser"`
+	AuthPass    string    `yaml:"authPass" json:"authPass"`
+	TLS         TLSConfig `yaml:"tls" json:"tls"`
+	PathPattern string    `yaml:"pathPattern" json:"pathPattern,omitempty"` // regex по tunnel/path, пусто = принимает всё
+
+	compiledPattern *regexp.Regexp
+}
+
+// Matches сообщает, относится ли данный tunnel/path к этому upstream'у.
+// Upstream без PathPattern принимает любой tunnel.
+func (u Upstream) Matches(tunnel string) bool {
+	if u.compiledPattern == nil {
+		return true
+	}
+	return u.compiledPattern.MatchString(tunnel)
+}
+
+// Config — полный список upstream'ов, как он лежит в CONFIG_FILE.
+type Config struct {
+	Upstreams []Upstream `yaml:"upstreams" json:"upstreams"`
+}
+
+// Load читает конфиг из path. Формат (YAML или JSON) определяется по
+// расширению файла — .json даёт JSON, всё остальное парсится как YAML.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения %s: %v", path, err)
+	}
+
+	var cfg Config
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			return nil, fmt.Errorf("ошибка парсинга JSON конфига %s: %v", path, err)
+		}
+	} else {
+		if err := yaml.Unmarshal(data, &cfg); err != nil {
+			return nil, fmt.Errorf("ошибка парсинга YAML конфига %s: %v", path, err)
+		}
+	}
+
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+func (cfg *Config) validate() error {
+	if len(cfg.Upstreams) == 0 {
+		return fmt.Errorf("конфиг не содержит ни одного upstream")
+	}
+
+	seen := make(map[string]bool, len(cfg.Upstreams))
+	for i := range cfg.Upstreams {
+		u := &cfg.Upstreams[i]
+		if u.Name == "" {
+			return fmt.Errorf("upstream #%d: name обязателен", i)
+		}
+		if seen[u.Name] {
+			return fmt.Errorf("upstream %q указан более одного раза", u.Name)
+		}
+		seen[u.Name] = true
+
+		if u.BaseURL == "" {
+			return fmt.Errorf("upstream %q: baseURL обязателен", u.Name)
+		}
+
+		if u.PathPattern != "" {
+			re, err := regexp.Compile(u.PathPattern)
+			if err != nil {
+				return fmt.Errorf("upstream %q: некорректный pathPattern: %v", u.Name, err)
+			}
+			u.compiledPattern = re
+		}
+	}
+	return nil
+}
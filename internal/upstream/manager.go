@@ -0,0 +1,174 @@
+package upstream
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/NotJohnPreston/monlightProxy/internal/mediamtx"
+	"github.com/NotJohnPreston/monlightProxy/internal/observability"
+)
+
+const (
+	defaultFailureThreshold = 3
+	defaultTripDuration     = 30 * time.Second
+)
+
+type entry struct {
+	upstream Upstream
+	client   *mediamtx.Client
+	breaker  *CircuitBreaker
+}
+
+// Entry — снимок рантайм-состояния одного upstream'а, отдаваемый вызывающему коду.
+type Entry struct {
+	Name     string
+	Client   *mediamtx.Client
+	Breaker  *CircuitBreaker
+	Upstream Upstream
+}
+
+// Manager хранит текущий набор upstream'ов и позволяет перечитать CONFIG_FILE
+// на лету (по SIGHUP), не прерывая уже идущие запросы: читатели всегда видят
+// целиком старый либо целиком новый набор через RWMutex, без промежуточных состояний.
+type Manager struct {
+	mu      sync.RWMutex
+	path    string // пусто, если Manager создан через NewStatic (legacy, без файла)
+	static  *Config
+	entries map[string]*entry
+	order   []string
+}
+
+// NewManager загружает конфиг из path и строит клиентов для каждого upstream.
+func NewManager(path string) (*Manager, error) {
+	m := &Manager{path: path}
+	if err := m.Reload(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// NewStatic строит Manager с единственным upstream без конфиг-файла —
+// используется, когда CONFIG_FILE не задан и прокси работает в legacy-режиме
+// одного BASE_URL/AUTH_USER/AUTH_PASS.
+func NewStatic(name, baseURL, authUser, authPass string) (*Manager, error) {
+	m := &Manager{static: &Config{Upstreams: []Upstream{{Name: name, BaseURL: baseURL, AuthUser: authUser, AuthPass: authPass}}}}
+	if err := m.Reload(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Reload перечитывает конфиг (с диска либо статический) и атомарно подменяет
+// набор upstream'ов. Клиенты, которые уже держат вызывающие обработчики,
+// довыполняют запрос как есть — подмена видна только следующим Entry()/All().
+func (m *Manager) Reload() error {
+	var cfg *Config
+	if m.path == "" {
+		cfg = m.static
+	} else {
+		loaded, err := Load(m.path)
+		if err != nil {
+			return err
+		}
+		cfg = loaded
+	}
+
+	entries := make(map[string]*entry, len(cfg.Upstreams))
+	order := make([]string, 0, len(cfg.Upstreams))
+	for _, u := range cfg.Upstreams {
+		client, err := mediamtx.NewClient(u.BaseURL, u.AuthUser, u.AuthPass)
+		if err != nil {
+			return fmt.Errorf("upstream %q: %v", u.Name, err)
+		}
+		transport, err := tlsTransport(u.TLS)
+		if err != nil {
+			return fmt.Errorf("upstream %q: %v", u.Name, err)
+		}
+		client.SetTransport(&observability.InstrumentedTransport{Base: transport})
+
+		entries[u.Name] = &entry{
+			upstream: u,
+			client:   client,
+			breaker:  NewCircuitBreaker(defaultFailureThreshold, defaultTripDuration),
+		}
+		order = append(order, u.Name)
+	}
+
+	m.mu.Lock()
+	m.entries = entries
+	m.order = order
+	m.mu.Unlock()
+	return nil
+}
+
+// tlsTransport строит http.RoundTripper с CA bundle / client cert, если они
+// заданы. Возвращает nil (что для InstrumentedTransport означает
+// http.DefaultTransport), если кастомный TLS не требуется.
+func tlsTransport(cfg TLSConfig) (http.RoundTripper, error) {
+	if cfg.CABundle == "" && cfg.ClientCert == "" && !cfg.InsecureSkipVerify {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: cfg.InsecureSkipVerify}
+
+	if cfg.CABundle != "" {
+		pem, err := os.ReadFile(cfg.CABundle)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка чтения caBundle: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("не удалось разобрать caBundle как PEM")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if cfg.ClientCert != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.ClientCert, cfg.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка загрузки client cert/key: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return &http.Transport{TLSClientConfig: tlsConfig}, nil
+}
+
+// Entry возвращает рантайм-состояние upstream'а с данным именем.
+func (m *Manager) Entry(name string) (Entry, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	e, ok := m.entries[name]
+	if !ok {
+		return Entry{}, false
+	}
+	return Entry{Name: name, Client: e.client, Breaker: e.breaker, Upstream: e.upstream}, true
+}
+
+// Default возвращает имя первого сконфигурированного upstream — используется
+// как upstream "по умолчанию" там, где имя явно не указано.
+func (m *Manager) Default() string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if len(m.order) == 0 {
+		return ""
+	}
+	return m.order[0]
+}
+
+// All возвращает снимок всех сконфигурированных upstream'ов в порядке из конфига.
+func (m *Manager) All() []Entry {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	out := make([]Entry, 0, len(m.order))
+	for _, name := range m.order {
+		e := m.entries[name]
+		out = append(out, Entry{Name: name, Client: e.client, Breaker: e.breaker, Upstream: e.upstream})
+	}
+	return out
+}
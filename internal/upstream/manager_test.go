@@ -0,0 +1,89 @@
+package upstream
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeConfig(t *testing.T, path, yaml string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(yaml), 0o644); err != nil {
+		t.Fatalf("ошибка записи конфига: %v", err)
+	}
+}
+
+func TestManagerReloadPicksUpConfigChanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "upstreams.yaml")
+	writeConfig(t, path, `
+upstreams:
+  - name: cam-1
+    baseURL: http://cam-1.local
+    authUser: u
+    authPass: p
+`)
+
+	m, err := NewManager(path)
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+
+	all := m.All()
+	if len(all) != 1 || all[0].Name != "cam-1" {
+		t.Fatalf("All() = %+v, want single entry cam-1", all)
+	}
+	if _, ok := m.Entry("cam-2"); ok {
+		t.Fatal("cam-2 не должен существовать до перезагрузки")
+	}
+
+	writeConfig(t, path, `
+upstreams:
+  - name: cam-1
+    baseURL: http://cam-1.local
+    authUser: u
+    authPass: p
+  - name: cam-2
+    baseURL: http://cam-2.local
+    authUser: u
+    authPass: p
+`)
+
+	if err := m.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+
+	all = m.All()
+	if len(all) != 2 {
+		t.Fatalf("после Reload All() = %+v, want 2 entries", all)
+	}
+	if _, ok := m.Entry("cam-2"); !ok {
+		t.Fatal("cam-2 должен появиться после Reload")
+	}
+}
+
+func TestManagerReloadKeepsOldSnapshotOnError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "upstreams.yaml")
+	writeConfig(t, path, `
+upstreams:
+  - name: cam-1
+    baseURL: http://cam-1.local
+    authUser: u
+    authPass: p
+`)
+
+	m, err := NewManager(path)
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+
+	writeConfig(t, path, `not: [valid`)
+
+	if err := m.Reload(); err == nil {
+		t.Fatal("Reload с битым YAML должен вернуть ошибку")
+	}
+
+	all := m.All()
+	if len(all) != 1 || all[0].Name != "cam-1" {
+		t.Fatalf("неудачный Reload не должен подменять старый снимок, got %+v", all)
+	}
+}
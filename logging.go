@@ -0,0 +1,20 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/NotJohnPreston/monlightProxy/internal/observability"
+)
+
+var logger *slog.Logger
+
+func initLogging() {
+	logger = observability.NewLogger()
+}
+
+// instrument оборачивает обработчик присвоением/проксированием X-Request-ID
+// и метриками Prometheus по входящим запросам прокси.
+func instrument(path string, next http.HandlerFunc) http.HandlerFunc {
+	return observability.RequestIDMiddleware(observability.InboundMiddleware(path, next))
+}
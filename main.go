@@ -1,18 +1,21 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
-	"net/http/cookiejar"
 	"os"
-	"strconv"
+	"os/signal"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
+	"github.com/NotJohnPreston/monlightProxy/internal/observability"
+	"github.com/NotJohnPreston/monlightProxy/internal/upstream"
 	"github.com/joho/godotenv"
 )
 
@@ -38,14 +41,43 @@ type ErrorResponse struct {
 }
 
 var (
-	baseURL             string
-	authUser            string
-	authPass            string
-	authenticatedClient *http.Client
-	clientMutex         sync.RWMutex
-	lastAuthTime        time.Time
+	// legacyMu защищает baseURL/authUser/authPass/upstreamsLoadedAt —
+	// testAPIConnection, debugHandler и getMTXClient читают их, а
+	// watchConfigReload переписывает по SIGHUP из отдельной горутины.
+	legacyMu          sync.RWMutex
+	baseURL           string
+	authUser          string
+	authPass          string
+	upstreamsLoadedAt time.Time
+
+	upstreams *upstream.Manager
 )
 
+// legacyCreds потокобезопасно возвращает baseURL/authUser/authPass upstream'а
+// "по умолчанию" — используется кодом, который обращается к MediaMTX напрямую
+// через net/http, в обход mediamtx.Client (диагностика в debugHandler/testAPIConnection).
+func legacyCreds() (base, user, pass string) {
+	legacyMu.RLock()
+	defer legacyMu.RUnlock()
+	return baseURL, authUser, authPass
+}
+
+// setUpstreamsLoadedAt потокобезопасно обновляет момент последней (пере)загрузки
+// upstream'ов — пишется из init()/watchConfigReload, читается из getMTXClient.
+func setUpstreamsLoadedAt(t time.Time) {
+	legacyMu.Lock()
+	upstreamsLoadedAt = t
+	legacyMu.Unlock()
+}
+
+// getUpstreamsLoadedAt потокобезопасно возвращает момент последней (пере)загрузки
+// upstream'ов.
+func getUpstreamsLoadedAt() time.Time {
+	legacyMu.RLock()
+	defer legacyMu.RUnlock()
+	return upstreamsLoadedAt
+}
+
 func init() {
 	if err := godotenv.Load(); err != nil {
 		log.Println("Предупреждение: .env файл не найден")
@@ -55,12 +87,61 @@ func init() {
 	authUser = os.Getenv("AUTH_USER")
 	authPass = os.Getenv("AUTH_PASS")
 
-	if baseURL == "" || authUser == "" || authPass == "" {
-		log.Fatal("Ошибка: BASE_URL, AUTH_USER и AUTH_PASS должны быть установлены в .env файле")
+	if err := loadUpstreams(); err != nil {
+		log.Fatalf("Ошибка загрузки конфигурации upstream'ов: %v", err)
+	}
+
+	initAuth()
+}
+
+// loadUpstreams строит upstreams либо из CONFIG_FILE (множественные
+// MediaMTX-инстансы с собственными TLS-настройками и circuit breaker'ами),
+// либо, если CONFIG_FILE не задан, из legacy-триплета
+// BASE_URL/AUTH_USER/AUTH_PASS в виде единственного upstream'а "default".
+func loadUpstreams() error {
+	configFile := os.Getenv("CONFIG_FILE")
+
+	var m *upstream.Manager
+	var err error
+	if configFile != "" {
+		m, err = upstream.NewManager(configFile)
+		if err != nil {
+			return fmt.Errorf("CONFIG_FILE=%s: %v", configFile, err)
+		}
+	} else {
+		if baseURL == "" || authUser == "" || authPass == "" {
+			return fmt.Errorf("установите CONFIG_FILE, либо BASE_URL, AUTH_USER и AUTH_PASS в .env файле")
+		}
+		m, err = upstream.NewStatic("default", baseURL, authUser, authPass)
+		if err != nil {
+			return err
+		}
+	}
+
+	upstreams = m
+	setUpstreamsLoadedAt(time.Now())
+	syncLegacyUpstreamVars()
+	return nil
+}
+
+// syncLegacyUpstreamVars подтягивает baseURL/authUser/authPass из upstream'а
+// "по умолчанию" — их по-прежнему читают testAPIConnection и debugHandler.
+func syncLegacyUpstreamVars() {
+	e, ok := upstreams.Entry(upstreams.Default())
+	if !ok {
+		return
 	}
+	legacyMu.Lock()
+	baseURL = e.Upstream.BaseURL
+	authUser = e.Upstream.AuthUser
+	authPass = e.Upstream.AuthPass
+	legacyMu.Unlock()
 }
 
 func main() {
+	initLogging()
+	initMockEngine()
+
 	// Проверяем, включен ли mock режим
 	mockMode := os.Getenv("MOCK_MODE")
 	if mockMode == "true" || mockMode == "1" {
@@ -80,22 +161,37 @@ func main() {
 		}
 	}
 
-	http.HandleFunc("/api/connections", getConnectionsHandler)
-	http.HandleFunc("/api/debug", debugHandler)
-	http.HandleFunc("/health", healthHandler)
+	go poller.Run(context.Background())
+	go watchConfigReload()
+
+	http.HandleFunc("/api/connections", instrument("/api/connections", requireAuth(getConnectionsHandler)))
+	http.HandleFunc("/api/connections/stream", instrument("/api/connections/stream", requireAuth(connectionsStreamHandler)))
+	http.HandleFunc("/api/debug", instrument("/api/debug", requireAuth(debugHandler)))
+	http.HandleFunc("/health", instrument("/health", requireAuth(healthHandler)))
+	http.HandleFunc("/openapi.yaml", openapiSpecHandler)
+	http.HandleFunc("/docs", docsHandler)
+	http.Handle("/metrics", observability.MetricsHandler())
+	registerMTXRoutes()
 
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8080"
 	}
 
+	bannerBaseURL, bannerAuthUser, _ := legacyCreds()
 	log.Printf("\n🚀 Сервер запущен на порту %s", port)
-	log.Printf("📍 BASE_URL: %s", baseURL)
-	log.Printf("👤 AUTH_USER: %s", authUser)
+	log.Printf("📍 BASE_URL: %s", bannerBaseURL)
+	log.Printf("👤 AUTH_USER: %s", bannerAuthUser)
+	log.Printf("🔀 Upstreams: %d (по умолчанию: %s)", len(upstreams.All()), upstreams.Default())
+	log.Printf("🛡️  AUTH_METHOD (для эндпоинтов прокси): %s", authMethod)
 	log.Printf("\n📚 Доступные эндпоинты:")
-	log.Printf("   GET http://localhost:%s/api/connections?page=1&itemsPerPage=10", port)
+	log.Printf("   GET http://localhost:%s/api/connections?page=1&itemsPerPage=10&upstream=<name>", port)
+	log.Printf("   GET http://localhost:%s/api/connections/stream - SSE/WebSocket поток изменений (poll %s)", port, pollInterval())
 	log.Printf("   GET http://localhost:%s/api/debug - отладочная информация", port)
 	log.Printf("   GET http://localhost:%s/health - health check", port)
+	log.Printf("   GET http://localhost:%s/api/paths, /api/rtspsessions, /api/rtmpconns, /api/webrtcsessions, /api/srtconns, /api/hlsmuxers, /api/recordings, /api/config/global", port)
+	log.Printf("   GET http://localhost:%s/openapi.yaml, /docs - спецификация API и Swagger UI", port)
+	log.Printf("   GET http://localhost:%s/metrics - метрики Prometheus", port)
 
 	if mockMode == "true" || mockMode == "1" {
 		log.Printf("\n🔧 Для отключения mock режима удалите MOCK_MODE из .env")
@@ -109,18 +205,38 @@ func main() {
 	}
 }
 
+// watchConfigReload перечитывает конфигурацию upstream'ов по SIGHUP, не
+// прерывая уже идущие запросы — Manager.Reload() атомарно подменяет набор
+// upstream'ов, а обработчики, уже получившие старый *mediamtx.Client,
+// довыполняют запрос как есть.
+func watchConfigReload() {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGHUP)
+	for range sigCh {
+		log.Println("🔁 SIGHUP: перечитываем конфигурацию upstream'ов")
+		if err := upstreams.Reload(); err != nil {
+			log.Printf("⚠️  Ошибка перезагрузки конфигурации upstream'ов: %v", err)
+			continue
+		}
+		setUpstreamsLoadedAt(time.Now())
+		syncLegacyUpstreamVars()
+		log.Println("✅ Конфигурация upstream'ов обновлена")
+	}
+}
+
 // Проверка доступности API
 func testAPIConnection() error {
+	base, user, pass := legacyCreds()
 	client := &http.Client{Timeout: 5 * time.Second}
 
 	// Пробуем получить список подключений
-	testURL := baseURL + "/api/v3/rtspconns/list?page=0&itemsPerPage=1"
+	testURL := base + "/api/v3/rtspconns/list?page=0&itemsPerPage=1"
 	req, err := http.NewRequest("GET", testURL, nil)
 	if err != nil {
 		return fmt.Errorf("ошибка создания запроса: %v", err)
 	}
 
-	req.SetBasicAuth(authUser, authPass)
+	req.SetBasicAuth(user, pass)
 	req.Header.Set("Accept", "application/json")
 
 	resp, err := client.Do(req)
@@ -152,55 +268,22 @@ func healthHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
 }
 
-// Получение или создание аутентифицированного HTTP клиента
-func getAuthenticatedClient() (*http.Client, error) {
-	clientMutex.RLock()
-	if authenticatedClient != nil && time.Since(lastAuthTime) < 50*time.Minute {
-		client := authenticatedClient
-		clientMutex.RUnlock()
-		return client, nil
-	}
-	clientMutex.RUnlock()
-
-	clientMutex.Lock()
-	defer clientMutex.Unlock()
-
-	// Двойная проверка
-	if authenticatedClient != nil && time.Since(lastAuthTime) < 50*time.Minute {
-		return authenticatedClient, nil
-	}
-
-	log.Println("Создание HTTP клиента...")
-
-	jar, err := cookiejar.New(nil)
-	if err != nil {
-		return nil, fmt.Errorf("ошибка создания cookie jar: %v", err)
-	}
-
-	client := &http.Client{
-		Jar:     jar,
-		Timeout: 30 * time.Second,
-	}
-
-	authenticatedClient = client
-	lastAuthTime = time.Now()
-
-	return client, nil
-}
-
 func debugHandler(w http.ResponseWriter, r *http.Request) {
-	client, err := getAuthenticatedClient()
+	reqLogger := observability.LoggerWithRequestID(logger, r.Context())
+
+	mtx, err := getMTXClient()
 	if err != nil {
 		sendError(w, http.StatusInternalServerError, fmt.Sprintf("Ошибка создания клиента: %v", err))
 		return
 	}
 
+	base, user, pass := legacyCreds()
 	results := make(map[string]interface{})
 
 	// Добавляем информацию о конфигурации
 	results["config"] = map[string]string{
-		"BASE_URL":  baseURL,
-		"AUTH_USER": authUser,
+		"BASE_URL":  base,
+		"AUTH_USER": user,
 		"MOCK_MODE": os.Getenv("MOCK_MODE"),
 	}
 
@@ -212,23 +295,23 @@ func debugHandler(w http.ResponseWriter, r *http.Request) {
 	}{
 		{
 			name:        "GET /api/v3/rtspconns/list",
-			url:         baseURL + "/api/v3/rtspconns/list?page=0&itemsPerPage=10",
+			url:         base + "/api/v3/rtspconns/list?page=0&itemsPerPage=10",
 			description: "Список RTSP подключений",
 		},
 		{
 			name:        "GET /api/v3/webrtcsessions/list",
-			url:         baseURL + "/api/v3/webrtcsessions/list?page=0&itemsPerPage=10",
+			url:         base + "/api/v3/webrtcsessions/list?page=0&itemsPerPage=10",
 			description: "Список WebRTC сессий",
 		},
 		{
 			name:        "GET /api/v3/rtspsessions/list",
-			url:         baseURL + "/api/v3/rtspsessions/list?page=0&itemsPerPage=10",
+			url:         base + "/api/v3/rtspsessions/list?page=0&itemsPerPage=10",
 			description: "Список RTSP сессий",
 		},
 	}
 
 	for _, tc := range testCases {
-		log.Printf("🧪 Тестирование: %s", tc.name)
+		reqLogger.Debug("тестирование эндпоинта", "name", tc.name, "url", tc.url)
 
 		req, err := http.NewRequest("GET", tc.url, nil)
 		if err != nil {
@@ -240,17 +323,17 @@ func debugHandler(w http.ResponseWriter, r *http.Request) {
 			continue
 		}
 
-		req.SetBasicAuth(authUser, authPass)
+		req.SetBasicAuth(user, pass)
 		req.Header.Set("Accept", "application/json")
 
-		resp, err := client.Do(req)
+		resp, err := mtx.HTTPClient().Do(req)
 		if err != nil {
 			results[tc.name] = map[string]interface{}{
 				"error":       err.Error(),
 				"url":         tc.url,
 				"description": tc.description,
 			}
-			log.Printf("   ❌ Ошибка: %v", err)
+			reqLogger.Warn("ошибка запроса к тестовому эндпоинту", "name", tc.name, "error", err)
 			continue
 		}
 
@@ -273,15 +356,15 @@ func debugHandler(w http.ResponseWriter, r *http.Request) {
 		// Если нашли JSON, выделяем это
 		if isJSON && resp.StatusCode == 200 {
 			result["✅ SUCCESS"] = true
-			log.Printf("   ✅ Успех! Статус: %d", resp.StatusCode)
+			reqLogger.Debug("тестовый эндпоинт успешен", "name", tc.name, "status", resp.StatusCode)
 		} else if resp.StatusCode == 401 {
 			result["⚠️ WARNING"] = "Ошибка аутентификации"
-			log.Printf("   ⚠️  401 Unauthorized - проверьте credentials")
+			reqLogger.Warn("401 Unauthorized — проверьте credentials", "name", tc.name)
 		} else if !isJSON {
 			result["⚠️ WARNING"] = "Получен не JSON ответ"
-			log.Printf("   ⚠️  Не JSON: %s", contentType)
+			reqLogger.Warn("получен не JSON ответ", "name", tc.name, "contentType", contentType)
 		} else {
-			log.Printf("   ❌ Статус: %d", resp.StatusCode)
+			reqLogger.Warn("неожиданный статус тестового эндпоинта", "name", tc.name, "status", resp.StatusCode)
 		}
 
 		results[tc.name] = result
@@ -292,155 +375,32 @@ func debugHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(results)
 }
 
+// getConnectionsHandler отдаёт список RTS-соединений из снимка, который в
+// фоне поддерживает connPoller — сам обработчик никогда не ходит в upstream,
+// это и даёт кэширование под частым опросом дашбордов. С ?upstream=<name>
+// отдаёт соединения только этого upstream'а, без параметра — объединённый
+// снимок по всем сконфигурированным upstream'ам.
 func getConnectionsHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		sendError(w, http.StatusMethodNotAllowed, "Разрешен только GET метод")
 		return
 	}
 
-	// Получение параметров
-	pageStr := r.URL.Query().Get("page")
-	itemsPerPageStr := r.URL.Query().Get("itemsPerPage")
-
-	// MediaMTX использует нумерацию страниц с 0, но для удобства пользователей мы принимаем с 1
-	page := 0
-	itemsPerPage := 100 // По умолчанию в MediaMTX
-
-	if pageStr != "" {
-		if p, err := strconv.Atoi(pageStr); err == nil && p > 0 {
-			page = p - 1 // Конвертируем в 0-based индекс для MediaMTX
-		}
-	}
+	reqLogger := observability.LoggerWithRequestID(logger, r.Context())
+	p := parsePageParams(r)
+	upstreamName := r.URL.Query().Get("upstream")
 
-	if itemsPerPageStr != "" {
-		if i, err := strconv.Atoi(itemsPerPageStr); err == nil && i > 0 {
-			itemsPerPage = i
-		}
-	}
-
-	// Проверяем mock режим
-	mockMode := os.Getenv("MOCK_MODE")
-	if mockMode == "true" || mockMode == "1" {
-		log.Printf("📦 Mock режим: возвращаем тестовые данные (page=%d, itemsPerPage=%d)", page+1, itemsPerPage)
-		mockResponse := generateMockData(page+1, itemsPerPage) // Конвертируем обратно для mock
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(mockResponse)
-		return
-	}
-
-	// Реальный запрос к API
-	client, err := getAuthenticatedClient()
-	if err != nil {
-		sendError(w, http.StatusInternalServerError, fmt.Sprintf("Ошибка аутентификации: %v. Попробуйте включить MOCK_MODE=true в .env", err))
-		return
-	}
-
-	apiURL := fmt.Sprintf("%s/api/v3/rtspconns/list?page=%d&itemsPerPage=%d", baseURL, page, itemsPerPage)
-	log.Printf("🔍 Запрос к API:")
-	log.Printf("   URL: %s", apiURL)
-	log.Printf("   Page: %d, ItemsPerPage: %d", page, itemsPerPage)
-	log.Printf("   Auth User: %s", authUser)
-
-	req, err := http.NewRequest("GET", apiURL, nil)
-	if err != nil {
-		sendError(w, http.StatusInternalServerError, "Ошибка создания запроса")
-		return
-	}
-
-	// MediaMTX использует Basic Auth для internal authentication
-	// Добавляем credentials к каждому запросу
-	req.SetBasicAuth(authUser, authPass)
-	req.Header.Set("Accept", "application/json")
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := client.Do(req)
+	items, err := poller.SnapshotFor(upstreamName)
 	if err != nil {
-		sendError(w, http.StatusBadGateway, fmt.Sprintf("Ошибка запроса: %v. Попробуйте MOCK_MODE=true", err))
+		sendError(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		sendError(w, http.StatusInternalServerError, "Ошибка чтения ответа")
-		return
-	}
+	items = filterConnectionsForCaller(items, claimsFromContext(r.Context()))
+	resp := paginateConnections(items, p)
 
-	log.Printf("📊 Ответ от MediaMTX:")
-	log.Printf("   Статус: %d", resp.StatusCode)
-	log.Printf("   Content-Type: %s", resp.Header.Get("Content-Type"))
-	log.Printf("   Размер ответа: %d байт", len(body))
-
-	if len(body) > 0 {
-		preview := string(body[:min(len(body), 500)])
-		log.Printf("   Тело ответа (первые 500 символов):\n%s", preview)
-	}
-
-	// Если получили HTML, пробуем сбросить сессию
-	if strings.Contains(string(body), "<!DOCTYPE html>") {
-		clientMutex.Lock()
-		authenticatedClient = nil
-		clientMutex.Unlock()
-		sendError(w, http.StatusUnauthorized, "API возвращает HTML вместо JSON. Возможно:\n1. Неверные credentials\n2. API недоступен\n3. Требуется другой метод аутентификации\n\nПопробуйте включить MOCK_MODE=true в .env для тестовых данных")
-		return
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(resp.StatusCode)
-		json.NewEncoder(w).Encode(ErrorResponse{
-			Error:   fmt.Sprintf("Статус %d", resp.StatusCode),
-			Message: string(body[:min(len(body), 200)]),
-		})
-		return
-	}
-
-	var connResponse RTSConnectionsResponse
-	if err := json.Unmarshal(body, &connResponse); err != nil {
-		sendError(w, http.StatusInternalServerError, fmt.Sprintf("Ошибка парсинга JSON: %v", err))
-		log.Printf("Не удалось распарсить: %s", string(body[:min(len(body), 1000)]))
-		return
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(connResponse)
-}
-
-// Генерация mock данных для тестирования
-func generateMockData(page, itemsPerPage int) RTSConnectionsResponse {
-	totalItems := 47 // Общее количество элементов
-	pageCount := (totalItems + itemsPerPage - 1) / itemsPerPage
-
-	// Рассчитываем, какие элементы показывать на этой странице
-	startIdx := (page - 1) * itemsPerPage
-	endIdx := startIdx + itemsPerPage
-	if endIdx > totalItems {
-		endIdx = totalItems
-	}
-
-	items := []RTSConnection{}
-
-	for i := startIdx; i < endIdx; i++ {
-		sessionID := fmt.Sprintf("session_%03d", i+1)
-		conn := RTSConnection{
-			ID:            fmt.Sprintf("conn_%03d", i+1),
-			Created:       time.Now().Add(-time.Duration(i) * time.Hour).Format(time.RFC3339),
-			RemoteAddr:    fmt.Sprintf("192.168.%d.%d:%d", (i/100)%256, i%256, 50000+i),
-			BytesReceived: int64((i + 1) * 1024 * 1024),
-			BytesSent:     int64((i + 1) * 2048 * 1024),
-			Session:       &sessionID, // nullable поле
-			Tunnel:        fmt.Sprintf("tunnel_%d", (i%5)+1),
-		}
-		items = append(items, conn)
-	}
-
-	return RTSConnectionsResponse{
-		PageCount: pageCount,
-		ItemCount: totalItems,
-		Items:     items,
-	}
+	reqLogger.Debug("отдаём закэшированный снимок соединений", "upstream", upstreamName, "page", p.Page+1, "itemsPerPage", p.ItemsPerPage, "itemCount", resp.ItemCount)
+	writeJSON(w, http.StatusOK, resp)
 }
 
 func sendError(w http.ResponseWriter, status int, message string) {
@@ -0,0 +1,335 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/NotJohnPreston/monlightProxy/internal/mediamtx"
+	"github.com/NotJohnPreston/monlightProxy/internal/observability"
+)
+
+// getMTXClient возвращает mediamtx.Client upstream'а "по умолчанию" —
+// используется обработчиками ресурсов MediaMTX, которые ещё не знают о
+// множественных upstream'ах (paths, sessions, конфиг и т.д.).
+func getMTXClient() (*mediamtx.Client, error) {
+	if upstreams == nil {
+		return nil, fmt.Errorf("upstream'ы не инициализированы")
+	}
+	observability.SetCachedClientAge(getUpstreamsLoadedAt())
+	e, ok := upstreams.Entry(upstreams.Default())
+	if !ok {
+		return nil, fmt.Errorf("нет доступных upstream'ов")
+	}
+	return e.Client, nil
+}
+
+// parsePageParams читает page/itemsPerPage из query-параметров и конвертирует
+// page из 1-based (удобно пользователям прокси) в 0-based (как ожидает MediaMTX).
+func parsePageParams(r *http.Request) mediamtx.PageParams {
+	page := 0
+	itemsPerPage := 100
+
+	if pageStr := r.URL.Query().Get("page"); pageStr != "" {
+		if p, err := strconv.Atoi(pageStr); err == nil && p > 0 {
+			page = p - 1
+		}
+	}
+	if ipp := r.URL.Query().Get("itemsPerPage"); ipp != "" {
+		if i, err := strconv.Atoi(ipp); err == nil && i > 0 {
+			itemsPerPage = i
+		}
+	}
+
+	return mediamtx.PageParams{Page: page, ItemsPerPage: itemsPerPage}
+}
+
+func isMockMode() bool {
+	mockMode := os.Getenv("MOCK_MODE")
+	return mockMode == "true" || mockMode == "1"
+}
+
+func pathsListHandler(w http.ResponseWriter, r *http.Request) {
+	p := parsePageParams(r)
+	if isMockMode() {
+		serveMock(w, r)
+		return
+	}
+	client, err := getMTXClient()
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, fmt.Sprintf("Ошибка создания клиента: %v", err))
+		return
+	}
+	resp, err := client.ListPaths(p)
+	if err != nil {
+		sendError(w, http.StatusBadGateway, fmt.Sprintf("Ошибка запроса paths/list: %v", err))
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func pathGetHandler(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/api/paths/")
+	if name == "" {
+		sendError(w, http.StatusBadRequest, "Не указано имя path")
+		return
+	}
+	if isMockMode() {
+		serveMock(w, r)
+		return
+	}
+	client, err := getMTXClient()
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, fmt.Sprintf("Ошибка создания клиента: %v", err))
+		return
+	}
+	resp, err := client.GetPath(name)
+	if err != nil {
+		sendError(w, http.StatusBadGateway, fmt.Sprintf("Ошибка запроса paths/get/%s: %v", name, err))
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func rtspSessionsListHandler(w http.ResponseWriter, r *http.Request) {
+	p := parsePageParams(r)
+	if isMockMode() {
+		serveMock(w, r)
+		return
+	}
+	client, err := getMTXClient()
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, fmt.Sprintf("Ошибка создания клиента: %v", err))
+		return
+	}
+	resp, err := client.ListRTSPSessions(p)
+	if err != nil {
+		sendError(w, http.StatusBadGateway, fmt.Sprintf("Ошибка запроса rtspsessions/list: %v", err))
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func rtmpConnsListHandler(w http.ResponseWriter, r *http.Request) {
+	p := parsePageParams(r)
+	if isMockMode() {
+		serveMock(w, r)
+		return
+	}
+	client, err := getMTXClient()
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, fmt.Sprintf("Ошибка создания клиента: %v", err))
+		return
+	}
+	resp, err := client.ListRTMPConns(p)
+	if err != nil {
+		sendError(w, http.StatusBadGateway, fmt.Sprintf("Ошибка запроса rtmpconns/list: %v", err))
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func webrtcSessionsListHandler(w http.ResponseWriter, r *http.Request) {
+	p := parsePageParams(r)
+	if isMockMode() {
+		serveMock(w, r)
+		return
+	}
+	client, err := getMTXClient()
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, fmt.Sprintf("Ошибка создания клиента: %v", err))
+		return
+	}
+	resp, err := client.ListWebRTCSessions(p)
+	if err != nil {
+		sendError(w, http.StatusBadGateway, fmt.Sprintf("Ошибка запроса webrtcsessions/list: %v", err))
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func srtConnsListHandler(w http.ResponseWriter, r *http.Request) {
+	p := parsePageParams(r)
+	if isMockMode() {
+		serveMock(w, r)
+		return
+	}
+	client, err := getMTXClient()
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, fmt.Sprintf("Ошибка создания клиента: %v", err))
+		return
+	}
+	resp, err := client.ListSRTConns(p)
+	if err != nil {
+		sendError(w, http.StatusBadGateway, fmt.Sprintf("Ошибка запроса srtconns/list: %v", err))
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func hlsMuxersListHandler(w http.ResponseWriter, r *http.Request) {
+	p := parsePageParams(r)
+	if isMockMode() {
+		serveMock(w, r)
+		return
+	}
+	client, err := getMTXClient()
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, fmt.Sprintf("Ошибка создания клиента: %v", err))
+		return
+	}
+	resp, err := client.ListHLSMuxers(p)
+	if err != nil {
+		sendError(w, http.StatusBadGateway, fmt.Sprintf("Ошибка запроса hlsmuxers/list: %v", err))
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func recordingsListHandler(w http.ResponseWriter, r *http.Request) {
+	p := parsePageParams(r)
+	if isMockMode() {
+		serveMock(w, r)
+		return
+	}
+	client, err := getMTXClient()
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, fmt.Sprintf("Ошибка создания клиента: %v", err))
+		return
+	}
+	resp, err := client.ListRecordings(p)
+	if err != nil {
+		sendError(w, http.StatusBadGateway, fmt.Sprintf("Ошибка запроса recordings/list: %v", err))
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func globalConfigHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		globalConfigGetHandler(w, r)
+	case http.MethodPatch:
+		globalConfigPatchHandler(w, r)
+	default:
+		sendError(w, http.StatusMethodNotAllowed, "Разрешены только GET и PATCH методы")
+	}
+}
+
+func globalConfigGetHandler(w http.ResponseWriter, r *http.Request) {
+	if isMockMode() {
+		serveMock(w, r)
+		return
+	}
+	client, err := getMTXClient()
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, fmt.Sprintf("Ошибка создания клиента: %v", err))
+		return
+	}
+	resp, err := client.GetGlobalConfig()
+	if err != nil {
+		sendError(w, http.StatusBadGateway, fmt.Sprintf("Ошибка запроса config/global/get: %v", err))
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func globalConfigPatchHandler(w http.ResponseWriter, r *http.Request) {
+	if !claimsFromContext(r.Context()).allowsAction("config.global.patch") {
+		sendError(w, http.StatusForbidden, `действие "config.global.patch" не разрешено для данного токена`)
+		return
+	}
+	var patch map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		sendError(w, http.StatusBadRequest, fmt.Sprintf("Некорректное тело запроса: %v", err))
+		return
+	}
+	if isMockMode() {
+		serveMock(w, r)
+		return
+	}
+	client, err := getMTXClient()
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, fmt.Sprintf("Ошибка создания клиента: %v", err))
+		return
+	}
+	if err := client.PatchGlobalConfig(patch); err != nil {
+		sendError(w, http.StatusBadGateway, fmt.Sprintf("Ошибка запроса config/global/patch: %v", err))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "patched"})
+}
+
+// kickHandler строит обработчик для одного из *.kick/{id} эндпоинтов MediaMTX.
+// В MOCK_MODE просто подтверждает приём команды, не имея реальной сессии для завершения.
+func kickHandler(pathPrefix string, kick func(client *mediamtx.Client, id string) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			sendError(w, http.StatusMethodNotAllowed, "Разрешен только POST метод")
+			return
+		}
+		id := strings.TrimPrefix(r.URL.Path, pathPrefix)
+		if id == "" {
+			sendError(w, http.StatusBadRequest, "Не указан id сессии/соединения")
+			return
+		}
+		if isMockMode() {
+			serveMock(w, r)
+			return
+		}
+		client, err := getMTXClient()
+		if err != nil {
+			sendError(w, http.StatusInternalServerError, fmt.Sprintf("Ошибка создания клиента: %v", err))
+			return
+		}
+		if err := kick(client, id); err != nil {
+			sendError(w, http.StatusBadGateway, fmt.Sprintf("Ошибка kick: %v", err))
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"status": "kicked", "id": id})
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// registerMTXRoutes регистрирует обработчики для полного набора ресурсов
+// MediaMTX v3 API поверх тех, что уже были у прокси.
+//
+// В отличие от /api/connections, ни один из этих ресурсов (включая
+// одиночный /api/paths/{name}, /api/hlsmuxers, /api/recordings и
+// /api/config/global) не фильтруется по Claims.Tunnels — они не кэшируют
+// собственный снимок, который можно было бы отфильтровать до пагинации
+// или выдачи, а просто проксируют ответ MediaMTX как есть, вплоть до
+// возврата конфигурации произвольного path по имени. Поэтому
+// requireUnrestrictedTunnels закрывает их для tunnel-ограниченных
+// токенов целиком: эти ресурсы — admin-only.
+func registerMTXRoutes() {
+	http.HandleFunc("/api/paths", instrument("/api/paths", requireAuth(requireUnrestrictedTunnels(pathsListHandler))))
+	http.HandleFunc("/api/paths/", instrument("/api/paths/", requireAuth(requireUnrestrictedTunnels(pathGetHandler))))
+	http.HandleFunc("/api/rtspsessions", instrument("/api/rtspsessions", requireAuth(requireUnrestrictedTunnels(rtspSessionsListHandler))))
+	http.HandleFunc("/api/rtspsessions/kick/", instrument("/api/rtspsessions/kick/", requireAuth(requireUnrestrictedTunnels(requireAction("rtspsessions.kick", kickHandler("/api/rtspsessions/kick/", func(c *mediamtx.Client, id string) error {
+		return c.KickRTSPSession(id)
+	}))))))
+	http.HandleFunc("/api/rtmpconns", instrument("/api/rtmpconns", requireAuth(requireUnrestrictedTunnels(rtmpConnsListHandler))))
+	http.HandleFunc("/api/rtmpconns/kick/", instrument("/api/rtmpconns/kick/", requireAuth(requireUnrestrictedTunnels(requireAction("rtmpconns.kick", kickHandler("/api/rtmpconns/kick/", func(c *mediamtx.Client, id string) error {
+		return c.KickRTMPConn(id)
+	}))))))
+	http.HandleFunc("/api/webrtcsessions", instrument("/api/webrtcsessions", requireAuth(requireUnrestrictedTunnels(webrtcSessionsListHandler))))
+	http.HandleFunc("/api/webrtcsessions/kick/", instrument("/api/webrtcsessions/kick/", requireAuth(requireUnrestrictedTunnels(requireAction("webrtcsessions.kick", kickHandler("/api/webrtcsessions/kick/", func(c *mediamtx.Client, id string) error {
+		return c.KickWebRTCSession(id)
+	}))))))
+	http.HandleFunc("/api/srtconns", instrument("/api/srtconns", requireAuth(requireUnrestrictedTunnels(srtConnsListHandler))))
+	http.HandleFunc("/api/srtconns/kick/", instrument("/api/srtconns/kick/", requireAuth(requireUnrestrictedTunnels(requireAction("srtconns.kick", kickHandler("/api/srtconns/kick/", func(c *mediamtx.Client, id string) error {
+		return c.KickSRTConn(id)
+	}))))))
+	http.HandleFunc("/api/hlsmuxers", instrument("/api/hlsmuxers", requireAuth(requireUnrestrictedTunnels(hlsMuxersListHandler))))
+	http.HandleFunc("/api/recordings", instrument("/api/recordings", requireAuth(requireUnrestrictedTunnels(recordingsListHandler))))
+	http.HandleFunc("/api/config/global", instrument("/api/config/global", requireAuth(requireUnrestrictedTunnels(globalConfigHandler))))
+}
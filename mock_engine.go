@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/NotJohnPreston/monlightProxy/internal/openapi"
+)
+
+var mockEngine *openapi.Engine
+
+// initMockEngine разбирает встроенную OpenAPI-спецификацию один раз при
+// старте. Используется только когда MOCK_MODE=true, но парсится всегда,
+// чтобы ошибка в спецификации была видна сразу, а не при первом запросе.
+func initMockEngine() {
+	engine, err := openapi.NewEngine(openapi.SpecYAML)
+	if err != nil {
+		log.Fatalf("Ошибка инициализации OpenAPI мок-движка: %v", err)
+	}
+	mockEngine = engine
+}
+
+// serveMock генерирует ответ по OpenAPI-спецификации для текущего запроса и
+// пишет его в w. Это единая точка входа для MOCK_MODE=true, заменяющая
+// прежние отдельные generateMockData/generateMockPaths/... генераторы.
+func serveMock(w http.ResponseWriter, r *http.Request) {
+	mock, err := mockEngine.Generate(r.Method, r.URL.Path, r.Header)
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, fmt.Sprintf("Ошибка мок-движка: %v", err))
+		return
+	}
+	writeJSON(w, mock.Status, mock.Body)
+}
+
+func openapiSpecHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/yaml")
+	w.Write(openapi.SpecYAML)
+}
+
+func docsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(w, swaggerUIPage)
+}
+
+const swaggerUIPage = `<!DOCTYPE html>
+<html>
+<head>
+  <title>monlightProxy API docs</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist/swagger-ui.css" />
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist/swagger-ui-bundle.js"></script>
+  <script>
+    window.onload = () => {
+      SwaggerUIBundle({ url: '/openapi.yaml', dom_id: '#swagger-ui' })
+    }
+  </script>
+</body>
+</html>
+`
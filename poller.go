@@ -0,0 +1,354 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NotJohnPreston/monlightProxy/internal/mediamtx"
+	"github.com/NotJohnPreston/monlightProxy/internal/upstream"
+)
+
+// ConnEvent — событие об изменении списка RTSP-соединений, которое поллер
+// рассылает подписчикам /api/connections/stream.
+type ConnEvent struct {
+	Type               string        `json:"type"` // added|removed|updated
+	Connection         RTSConnection `json:"connection"`
+	BytesReceivedDelta int64         `json:"bytesReceivedDelta,omitempty"`
+	BytesSentDelta     int64         `json:"bytesSentDelta,omitempty"`
+}
+
+const subscriberBufferSize = 32
+
+// connPoller периодически опрашивает rtspconns/list у каждого сконфигурированного
+// upstream'а, держит в памяти как объединённый снимок, так и снимок на
+// upstream (чтобы GET /api/connections?upstream=<name> не ходил в сеть), и
+// рассылает added/removed/updated события подписчикам стрима.
+type connPoller struct {
+	mu          sync.RWMutex
+	snapshot    map[string]RTSConnection            // ключ "upstream/id" — для дедупликации ID между upstream'ами
+	perUpstream map[string]map[string]RTSConnection // upstream -> id -> соединение
+
+	subMu       sync.Mutex
+	subscribers map[chan ConnEvent]struct{}
+}
+
+func newConnPoller() *connPoller {
+	return &connPoller{
+		snapshot:    make(map[string]RTSConnection),
+		perUpstream: make(map[string]map[string]RTSConnection),
+		subscribers: make(map[chan ConnEvent]struct{}),
+	}
+}
+
+var poller = newConnPoller()
+
+// Subscribe регистрирует нового подписчика и возвращает канал событий и
+// функцию отписки.
+func (p *connPoller) Subscribe() (<-chan ConnEvent, func()) {
+	ch := make(chan ConnEvent, subscriberBufferSize)
+	p.subMu.Lock()
+	p.subscribers[ch] = struct{}{}
+	p.subMu.Unlock()
+
+	unsubscribe := func() {
+		p.subMu.Lock()
+		if _, ok := p.subscribers[ch]; ok {
+			delete(p.subscribers, ch)
+			close(ch)
+		}
+		p.subMu.Unlock()
+	}
+	return ch, unsubscribe
+}
+
+// publish рассылает событие всем подписчикам. Подписчик с переполненным
+// буфером считается медленным потребителем и отключается, чтобы не тормозить
+// сам поллер.
+func (p *connPoller) publish(event ConnEvent) {
+	p.subMu.Lock()
+	defer p.subMu.Unlock()
+
+	for ch := range p.subscribers {
+		select {
+		case ch <- event:
+		default:
+			delete(p.subscribers, ch)
+			close(ch)
+			logger.Warn("отписан медленный подписчик /api/connections/stream")
+		}
+	}
+}
+
+// Snapshot отдаёт объединённый (по всем upstream'ам) список соединений,
+// отсортированный по ID для стабильной пагинации.
+func (p *connPoller) Snapshot() []RTSConnection {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return sortedValues(p.snapshot)
+}
+
+// SnapshotFor отдаёт список соединений одного upstream'а по имени. Пустое имя
+// равносильно Snapshot() — объединённому списку по всем upstream'ам.
+func (p *connPoller) SnapshotFor(name string) ([]RTSConnection, error) {
+	if name == "" {
+		return p.Snapshot(), nil
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	byID, ok := p.perUpstream[name]
+	if !ok {
+		return nil, fmt.Errorf("неизвестный upstream %q", name)
+	}
+	return sortedValues(byID), nil
+}
+
+func sortedValues(m map[string]RTSConnection) []RTSConnection {
+	items := make([]RTSConnection, 0, len(m))
+	for _, conn := range m {
+		items = append(items, conn)
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].ID < items[j].ID })
+	return items
+}
+
+// Run запускает бесконечный цикл опроса с интервалом POLL_INTERVAL, пока ctx
+// не будет отменён.
+func (p *connPoller) Run(ctx context.Context) {
+	interval := pollInterval()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	p.pollOnce()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.pollOnce()
+		}
+	}
+}
+
+func pollInterval() time.Duration {
+	raw := os.Getenv("POLL_INTERVAL")
+	if raw == "" {
+		return 2 * time.Second
+	}
+	if d, err := time.ParseDuration(raw); err == nil {
+		return d
+	}
+	if seconds, err := strconv.Atoi(raw); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	return 2 * time.Second
+}
+
+func (p *connPoller) pollOnce() {
+	if isMockMode() {
+		items, err := fetchMockConnections()
+		if err != nil {
+			logger.Warn("ошибка опроса mock-данных", "error", err)
+			return
+		}
+		p.diffAndSwap(map[string][]RTSConnection{"mock": items})
+		return
+	}
+
+	if upstreams == nil {
+		return
+	}
+
+	entries := upstreams.All()
+
+	// Опрашиваем все upstream'ы параллельно, чтобы один медленный/мёртвый узел
+	// не растягивал цикл опроса на сумму всех таймаутов.
+	type pollResult struct {
+		name  string
+		items []RTSConnection
+		err   error
+	}
+	results := make(chan pollResult, len(entries))
+	for _, e := range entries {
+		go func(e upstream.Entry) {
+			if !e.Breaker.Allow() {
+				results <- pollResult{name: e.Name, err: fmt.Errorf("circuit breaker открыт")}
+				return
+			}
+			items, err := listAllConnections(e.Client)
+			if err != nil {
+				e.Breaker.RecordFailure()
+				results <- pollResult{name: e.Name, err: err}
+				return
+			}
+			e.Breaker.RecordSuccess()
+			results <- pollResult{name: e.Name, items: filterByPattern(items, e.Upstream)}
+		}(e)
+	}
+
+	perUpstream := make(map[string][]RTSConnection, len(entries))
+	for range entries {
+		r := <-results
+		if r.err != nil {
+			logger.Warn("ошибка опроса rtspconns/list, оставляем предыдущий снимок", "upstream", r.name, "error", r.err)
+			// Сохраняем последний успешный снимок этого upstream'а — иначе
+			// единичный сбой стирает все его соединения как "removed" и
+			// SnapshotFor(name) начинает ошибочно отвечать "неизвестный upstream".
+			if prev, err := p.SnapshotFor(r.name); err == nil {
+				perUpstream[r.name] = prev
+			}
+			continue
+		}
+		perUpstream[r.name] = r.items
+	}
+	p.diffAndSwap(perUpstream)
+}
+
+// filterByPattern оставляет только те соединения, tunnel которых подходит под
+// PathPattern данного upstream'а — так задание pathPattern в конфиге реально
+// маршрутизирует, какие tunnel'ы относятся к какому upstream'у.
+func filterByPattern(items []RTSConnection, u upstream.Upstream) []RTSConnection {
+	filtered := make([]RTSConnection, 0, len(items))
+	for _, conn := range items {
+		if u.Matches(conn.Tunnel) {
+			filtered = append(filtered, conn)
+		}
+	}
+	return filtered
+}
+
+// fetchMockConnections возвращает список соединений из мок-движка — в
+// MOCK_MODE upstream'ов нет, есть единственный виртуальный снимок "mock".
+//
+// Без Prefer движок падает на схемный example RTSConnection (единственное
+// статичное соединение conn_001), и пагинация/диффинг не из чего проверить.
+// Запрашиваем именованный example "populated" (несколько соединений из
+// spec.yaml) и докидываем byte-counter'ам немного случайного роста, чтобы
+// опрос к опросу менялось хоть что-то и diffAndSwap реально рассылал
+// "updated", как при живых upstream'ах.
+func fetchMockConnections() ([]RTSConnection, error) {
+	header := http.Header{"Prefer": []string{"example=populated"}}
+	mock, err := mockEngine.Generate(http.MethodGet, "/api/connections", header)
+	if err != nil {
+		return nil, err
+	}
+	var resp RTSConnectionsResponse
+	raw, _ := json.Marshal(mock.Body)
+	if err := json.Unmarshal(raw, &resp); err != nil {
+		return nil, err
+	}
+	for i := range resp.Items {
+		resp.Items[i].BytesReceived += int64(rand.Intn(4096))
+		resp.Items[i].BytesSent += int64(rand.Intn(4096))
+	}
+	return resp.Items, nil
+}
+
+// listAllConnections листает rtspconns/list одного upstream'а целиком (все
+// страницы) и возвращает полный список его соединений.
+func listAllConnections(client *mediamtx.Client) ([]RTSConnection, error) {
+	const itemsPerPage = 100
+	var all []RTSConnection
+	page := 0
+	for {
+		body, status, err := client.ListConnections(mediamtx.PageParams{Page: page, ItemsPerPage: itemsPerPage})
+		if err != nil {
+			return nil, err
+		}
+
+		if strings.Contains(string(body), "<!DOCTYPE html>") {
+			return nil, fmt.Errorf("API вернул HTML вместо JSON — неверные credentials или требуется другой метод аутентификации")
+		}
+
+		if status != http.StatusOK {
+			return nil, fmt.Errorf("rtspconns/list вернул статус %d", status)
+		}
+
+		var resp RTSConnectionsResponse
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return nil, fmt.Errorf("ошибка парсинга JSON: %v", err)
+		}
+
+		all = append(all, resp.Items...)
+		page++
+		if page >= resp.PageCount || len(resp.Items) == 0 {
+			break
+		}
+	}
+	return all, nil
+}
+
+// diffAndSwap обновляет как объединённый снимок, так и снимок на upstream, и
+// рассылает added/removed/updated события, сравнивая с предыдущим состоянием
+// по ключу "upstream/id" (чтобы совпадающие ID с разных upstream'ов не
+// затирали друг друга).
+func (p *connPoller) diffAndSwap(perUpstream map[string][]RTSConnection) {
+	nextCombined := make(map[string]RTSConnection)
+	nextPerUpstream := make(map[string]map[string]RTSConnection, len(perUpstream))
+
+	for upstreamName, items := range perUpstream {
+		byID := make(map[string]RTSConnection, len(items))
+		for _, conn := range items {
+			byID[conn.ID] = conn
+			nextCombined[upstreamName+"/"+conn.ID] = conn
+		}
+		nextPerUpstream[upstreamName] = byID
+	}
+
+	p.mu.Lock()
+	prevCombined := p.snapshot
+	p.snapshot = nextCombined
+	p.perUpstream = nextPerUpstream
+	p.mu.Unlock()
+
+	for key, conn := range nextCombined {
+		old, existed := prevCombined[key]
+		if !existed {
+			p.publish(ConnEvent{Type: "added", Connection: conn})
+			continue
+		}
+		if old.BytesReceived != conn.BytesReceived || old.BytesSent != conn.BytesSent {
+			p.publish(ConnEvent{
+				Type:               "updated",
+				Connection:         conn,
+				BytesReceivedDelta: conn.BytesReceived - old.BytesReceived,
+				BytesSentDelta:     conn.BytesSent - old.BytesSent,
+			})
+		}
+	}
+	for key, conn := range prevCombined {
+		if _, stillThere := nextCombined[key]; !stillThere {
+			p.publish(ConnEvent{Type: "removed", Connection: conn})
+		}
+	}
+}
+
+// paginateConnections нарезает уже загруженный список соединений на страницу,
+// не делая ни одного обращения к upstream.
+func paginateConnections(items []RTSConnection, p mediamtx.PageParams) RTSConnectionsResponse {
+	totalItems := len(items)
+	pageCount := 0
+	if p.ItemsPerPage > 0 {
+		pageCount = (totalItems + p.ItemsPerPage - 1) / p.ItemsPerPage
+	}
+
+	start := p.Page * p.ItemsPerPage
+	end := start + p.ItemsPerPage
+	if start > totalItems {
+		start = totalItems
+	}
+	if end > totalItems {
+		end = totalItems
+	}
+
+	return RTSConnectionsResponse{PageCount: pageCount, ItemCount: totalItems, Items: items[start:end]}
+}
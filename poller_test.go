@@ -0,0 +1,83 @@
+package main
+
+import "testing"
+
+// drain собирает все события, уже лежащие в канале подписчика, не блокируясь.
+func drain(ch <-chan ConnEvent) []ConnEvent {
+	var events []ConnEvent
+	for {
+		select {
+		case e := <-ch:
+			events = append(events, e)
+		default:
+			return events
+		}
+	}
+}
+
+func TestDiffAndSwapPublishesAdded(t *testing.T) {
+	p := newConnPoller()
+	ch, unsubscribe := p.Subscribe()
+	defer unsubscribe()
+
+	p.diffAndSwap(map[string][]RTSConnection{
+		"cam-1": {{ID: "1", Tunnel: "cam-1", BytesReceived: 100}},
+	})
+
+	events := drain(ch)
+	if len(events) != 1 || events[0].Type != "added" || events[0].Connection.ID != "1" {
+		t.Fatalf("events = %+v, want single added event for id 1", events)
+	}
+}
+
+func TestDiffAndSwapPublishesUpdatedOnByteDelta(t *testing.T) {
+	p := newConnPoller()
+	p.diffAndSwap(map[string][]RTSConnection{
+		"cam-1": {{ID: "1", Tunnel: "cam-1", BytesReceived: 100, BytesSent: 50}},
+	})
+
+	ch, unsubscribe := p.Subscribe()
+	defer unsubscribe()
+
+	p.diffAndSwap(map[string][]RTSConnection{
+		"cam-1": {{ID: "1", Tunnel: "cam-1", BytesReceived: 150, BytesSent: 80}},
+	})
+
+	events := drain(ch)
+	if len(events) != 1 || events[0].Type != "updated" {
+		t.Fatalf("events = %+v, want single updated event", events)
+	}
+	if events[0].BytesReceivedDelta != 50 || events[0].BytesSentDelta != 30 {
+		t.Errorf("deltas = (%d, %d), want (50, 30)", events[0].BytesReceivedDelta, events[0].BytesSentDelta)
+	}
+}
+
+func TestDiffAndSwapPublishesRemoved(t *testing.T) {
+	p := newConnPoller()
+	p.diffAndSwap(map[string][]RTSConnection{
+		"cam-1": {{ID: "1", Tunnel: "cam-1"}},
+	})
+
+	ch, unsubscribe := p.Subscribe()
+	defer unsubscribe()
+
+	p.diffAndSwap(map[string][]RTSConnection{})
+
+	events := drain(ch)
+	if len(events) != 1 || events[0].Type != "removed" || events[0].Connection.ID != "1" {
+		t.Fatalf("events = %+v, want single removed event for id 1", events)
+	}
+}
+
+func TestDiffAndSwapKeepsIDsSeparateAcrossUpstreams(t *testing.T) {
+	p := newConnPoller()
+	p.diffAndSwap(map[string][]RTSConnection{
+		"cam-1": {{ID: "1", Tunnel: "cam-1"}},
+		"cam-2": {{ID: "1", Tunnel: "cam-2"}},
+	})
+
+	combined := p.Snapshot()
+	if len(combined) != 2 {
+		t.Fatalf("Snapshot() = %+v, want 2 connections (same ID, different upstreams)", combined)
+	}
+}
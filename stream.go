@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/websocket"
+)
+
+var wsUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin:     func(r *http.Request) bool { return true },
+}
+
+// connectionsStreamHandler отдаёт инкрементальные added/removed/updated события
+// по списку RTS-соединений — по умолчанию через SSE, либо через WebSocket,
+// если клиент прислал заголовки апгрейда.
+func connectionsStreamHandler(w http.ResponseWriter, r *http.Request) {
+	if websocket.IsWebSocketUpgrade(r) {
+		serveConnectionsWebSocket(w, r)
+		return
+	}
+	serveConnectionsSSE(w, r)
+}
+
+func serveConnectionsSSE(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		sendError(w, http.StatusInternalServerError, "Стриминг не поддерживается этим ResponseWriter")
+		return
+	}
+
+	ch, unsubscribe := poller.Subscribe()
+	defer unsubscribe()
+
+	claims := claimsFromContext(r.Context())
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-ch:
+			if !ok {
+				return
+			}
+			if !claims.allowsTunnel(event.Connection.Tunnel) {
+				continue
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+			flusher.Flush()
+		}
+	}
+}
+
+func serveConnectionsWebSocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := wsUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		logger.Warn("ошибка апгрейда /api/connections/stream до WebSocket", "error", err)
+		return
+	}
+	defer conn.Close()
+
+	ch, unsubscribe := poller.Subscribe()
+	defer unsubscribe()
+
+	claims := claimsFromContext(r.Context())
+
+	for event := range ch {
+		if !claims.allowsTunnel(event.Connection.Tunnel) {
+			continue
+		}
+		if err := conn.WriteJSON(event); err != nil {
+			return
+		}
+	}
+}